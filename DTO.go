@@ -1,9 +1,19 @@
 package users
 
 type UserRegisterInput struct {
-	Email    string
-	Username string
-	Password string
+	Email       string
+	Username    string
+	DisplayName string
+	Password    string
+}
+
+// UserPatch changes the profile fields that are set and leaves the rest
+// alone. Password, role, status and policy attributes are deliberately not
+// patchable; they change only through their dedicated Service methods.
+type UserPatch struct {
+	Email       *string
+	Username    *string
+	DisplayName *string
 }
 
 type UserLoginInput struct {
@@ -0,0 +1,164 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type UserStatus string
+
+const (
+	StatusActive    UserStatus = "active"
+	StatusSuspended UserStatus = "suspended"
+	StatusBanned    UserStatus = "banned"
+)
+
+// IsRestricted reports whether the user is suspended or banned at now. A
+// suspension whose expiry has passed no longer restricts the user.
+func (u *User) IsRestricted(now time.Time) bool {
+	switch u.Status {
+	case StatusSuspended:
+		return u.StatusExpiresAt == nil || now.Before(*u.StatusExpiresAt)
+	case StatusBanned:
+		return true
+	}
+	return false
+}
+
+// AccountSuspendedError carries the details of a suspension or ban. It
+// matches ErrAccountSuspended with errors.Is.
+type AccountSuspendedError struct {
+	Status    UserStatus
+	Reason    string
+	ExpiresAt *time.Time
+}
+
+func (e *AccountSuspendedError) Error() string {
+	msg := fmt.Sprintf("account %s", e.Status)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	if e.ExpiresAt != nil {
+		msg += " until " + e.ExpiresAt.Format(time.RFC3339)
+	}
+	return msg
+}
+
+func (e *AccountSuspendedError) Unwrap() error {
+	return ErrAccountSuspended
+}
+
+// SuspendUser blocks the user from logging in. A nil until suspends the
+// account indefinitely.
+func (s *Service) SuspendUser(ctx context.Context, userID, reason string, until *time.Time) (*User, error) {
+	return s.setAccountStatus(ctx, userID, StatusSuspended, reason, until)
+}
+
+func (s *Service) BanUser(ctx context.Context, userID, reason string) (*User, error) {
+	return s.setAccountStatus(ctx, userID, StatusBanned, reason, nil)
+}
+
+func (s *Service) ReinstateUser(ctx context.Context, userID string) (*User, error) {
+	return s.setAccountStatus(ctx, userID, StatusActive, "", nil)
+}
+
+func (s *Service) setAccountStatus(ctx context.Context, userID string, status UserStatus, reason string, until *time.Time) (*User, error) {
+	if err := s.authorizeUser(ctx, PermissionUsersSuspend, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.activeUser(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		actor, _ := ActorFromContext(ctx)
+		before := *user
+		user.Status = status
+		user.StatusReason = reason
+		user.StatusActorID = actor.UserID
+		user.StatusExpiresAt = until
+
+		updatedUser, err := s.userRepo.Update(ctx, *user)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateUser, err)
+		}
+
+		if err := s.audit(ctx, AuditUserStatusChanged, "user", userID, before, updatedUser); err != nil {
+			return nil, err
+		}
+		err = s.publish(ctx, UserStatusChanged{
+			EventMeta: s.eventMeta(ctx, userID),
+			Status:    status,
+			Reason:    reason,
+			ExpiresAt: until,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return updatedUser, nil
+	})
+}
+
+// LiftExpiredSuspensions reinstates every user whose suspension has expired
+// and returns how many were reinstated.
+func (s *Service) LiftExpiredSuspensions(ctx context.Context) (int, error) {
+	if err := s.authorize(ctx, PermissionUsersSuspend, Resource{Type: "user"}); err != nil {
+		return 0, err
+	}
+
+	users, err := s.userRepo.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
+	}
+
+	lifted := 0
+	for i := range users {
+		user := &users[i]
+		if user.Status != StatusSuspended || user.IsRestricted(s.now()) {
+			continue
+		}
+		if err := s.liftSuspension(ctx, user); err != nil {
+			return lifted, err
+		}
+		lifted++
+	}
+	return lifted, nil
+}
+
+// checkAccountStatus rejects restricted users and clears suspensions that
+// have run out.
+func (s *Service) checkAccountStatus(ctx context.Context, user *User) error {
+	if user.IsRestricted(s.now()) {
+		return &AccountSuspendedError{
+			Status:    user.Status,
+			Reason:    user.StatusReason,
+			ExpiresAt: user.StatusExpiresAt,
+		}
+	}
+	if user.Status == StatusSuspended {
+		return s.liftSuspension(ctx, user)
+	}
+	return nil
+}
+
+func (s *Service) liftSuspension(ctx context.Context, user *User) error {
+	return s.withinTx(ctx, func(ctx context.Context) error {
+		before := *user
+		user.Status = StatusActive
+		user.StatusReason = ""
+		user.StatusActorID = ""
+		user.StatusExpiresAt = nil
+
+		if _, err := s.userRepo.Update(ctx, *user); err != nil {
+			return updateError(ErrFailedToUpdateUser, err)
+		}
+
+		if err := s.audit(ctx, AuditUserStatusChanged, "user", user.ID, before, user); err != nil {
+			return err
+		}
+		return s.publish(ctx, UserStatusChanged{EventMeta: s.eventMeta(ctx, user.ID), Status: StatusActive})
+	})
+}
@@ -0,0 +1,120 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountSuspension(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := &testClock{now: now}
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"mod":   {ID: "mod", RoleID: "r-admin"},
+		"alice": {ID: "alice", Email: "alice@example.com", HashedPassword: "hashed:pw"},
+	}}
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{"r-admin": {ID: "r-admin", Name: RoleAdmin}}}
+	svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithClock(clock.Now))
+	ctx := actorCtx("mod")
+	login := UserLoginInput{Email: "alice@example.com", Password: "pw"}
+
+	t.Run("suspended login returns details", func(t *testing.T) {
+		until := now.Add(24 * time.Hour)
+		u, err := svc.SuspendUser(ctx, "alice", "spam", &until)
+		require.NoError(t, err)
+		require.Equal(t, StatusSuspended, u.Status)
+		require.Equal(t, "mod", u.StatusActorID)
+
+		_, err = svc.Login(context.Background(), login)
+		require.ErrorIs(t, err, ErrAccountSuspended)
+		var suspended *AccountSuspendedError
+		require.True(t, errors.As(err, &suspended))
+		require.Equal(t, "spam", suspended.Reason)
+		require.Equal(t, until, *suspended.ExpiresAt)
+	})
+
+	t.Run("wrong password does not reveal suspension", func(t *testing.T) {
+		_, err := svc.Login(context.Background(), UserLoginInput{Email: "alice@example.com", Password: "wrong"})
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("expired suspension is lifted on login", func(t *testing.T) {
+		clock.now = now.Add(25 * time.Hour)
+		defer func() { clock.now = now }()
+
+		_, err := svc.Login(context.Background(), login)
+		require.NoError(t, err)
+		require.Equal(t, StatusActive, userRepo.users["alice"].Status)
+		require.Nil(t, userRepo.users["alice"].StatusExpiresAt)
+	})
+
+	t.Run("ban", func(t *testing.T) {
+		_, err := svc.BanUser(ctx, "alice", "fraud")
+		require.NoError(t, err)
+		_, err = svc.Login(context.Background(), login)
+		var suspended *AccountSuspendedError
+		require.True(t, errors.As(err, &suspended))
+		require.Equal(t, StatusBanned, suspended.Status)
+		require.Nil(t, suspended.ExpiresAt)
+	})
+
+	t.Run("reinstate", func(t *testing.T) {
+		_, err := svc.ReinstateUser(ctx, "alice")
+		require.NoError(t, err)
+		_, err = svc.Login(context.Background(), login)
+		require.NoError(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := svc.SuspendUser(ctx, "ghost", "spam", nil)
+		require.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestLiftExpiredSuspensions(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"expired":    {ID: "expired", Status: StatusSuspended, StatusExpiresAt: &past},
+		"ongoing":    {ID: "ongoing", Status: StatusSuspended, StatusExpiresAt: &future},
+		"indefinite": {ID: "indefinite", Status: StatusSuspended},
+		"banned":     {ID: "banned", Status: StatusBanned},
+	}}
+	svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{}, WithClock(func() time.Time { return now }))
+
+	n, err := svc.LiftExpiredSuspensions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, StatusActive, userRepo.users["expired"].Status)
+	require.Equal(t, StatusSuspended, userRepo.users["ongoing"].Status)
+	require.Equal(t, StatusSuspended, userRepo.users["indefinite"].Status)
+	require.Equal(t, StatusBanned, userRepo.users["banned"].Status)
+}
+
+func TestSuspendedActorIsForbidden(t *testing.T) {
+	svc, userRepo := newEnforcingService()
+	userRepo.users["admin"].Status = StatusBanned
+	defer func() { userRepo.users["admin"].Status = "" }()
+
+	_, err := svc.ListUsers(actorCtx("admin"))
+	require.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestUpdateUserKeepsAccountStatus(t *testing.T) {
+	svc, userRepo := newEnforcingService()
+	_, err := svc.BanUser(actorCtx("admin"), "bob", "abuse")
+	require.NoError(t, err)
+
+	bob := *userRepo.users["bob"]
+	bob.Status, bob.StatusReason, bob.StatusActorID = StatusActive, "", ""
+	bob.DisplayName = "Bob"
+	updated, err := svc.UpdateUser(actorCtx("admin"), bob)
+	require.NoError(t, err)
+	require.Equal(t, "Bob", updated.DisplayName)
+	require.Equal(t, StatusBanned, updated.Status)
+	require.Equal(t, "abuse", updated.StatusReason)
+	require.Equal(t, "admin", updated.StatusActorID)
+}
@@ -0,0 +1,318 @@
+package users
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	AuditUserRegistered    = "user.registered"
+	AuditUserUpdated       = "user.updated"
+	AuditProfileUpdated    = "user.profile_updated"
+	AuditPasswordChanged   = "user.password_changed"
+	AuditPasswordReset     = "user.password_reset"
+	AuditRoleAssigned      = "user.role_assigned"
+	AuditUserStatusChanged = "user.status_changed"
+	AuditUserDeleted       = "user.deleted"
+	AuditUserRestored      = "user.restored"
+	AuditUserPurged        = "user.purged"
+	AuditUserErased        = "user.erased"
+
+	AuditRoleCreated = "role.created"
+	AuditRoleUpdated = "role.updated"
+
+	AuditOrganizationCreated = "organization.created"
+	AuditMemberAdded         = "organization.member_added"
+	AuditMemberRemoved       = "organization.member_removed"
+	AuditMemberRoleChanged   = "organization.member_role_changed"
+	AuditInvitationCreated   = "invitation.created"
+	AuditInvitationAccepted  = "invitation.accepted"
+	AuditInvitationRevoked   = "invitation.revoked"
+
+	AuditGroupCreated       = "group.created"
+	AuditGroupDeleted       = "group.deleted"
+	AuditGroupMemberAdded   = "group.member_added"
+	AuditGroupMemberRemoved = "group.member_removed"
+	AuditGroupRolesChanged  = "group.roles_changed"
+
+	AuditRelationGranted = "relation.granted"
+	AuditRelationRevoked = "relation.revoked"
+
+	AuditWebhookCreated = "webhook.created"
+	AuditWebhookDeleted = "webhook.deleted"
+)
+
+// EnvironmentClientIP is the ContextWithEnvironment key audit entries take the
+// client IP from.
+const EnvironmentClientIP = "ip"
+
+// redactedAuditFields never have their values written to the audit log; a
+// change to them is still recorded. Besides secrets this covers personal
+// data: entries are hash-chained and cannot be rewritten, so any value stored
+// in them would outlive EraseUser. Entries identify users by ID only.
+var redactedAuditFields = map[string]bool{
+	"HashedPassword": true,
+	"Secret":         true,
+	"Email":          true,
+	"Username":       true,
+	"DisplayName":    true,
+	"Attributes":     true,
+}
+
+var redactedAuditValue = json.RawMessage(`"[redacted]"`)
+
+// AuditEntry records one Service mutation. Seq, PrevHash and Hash link the
+// entry to its predecessor; see LinkAuditEntry and VerifyChain.
+type AuditEntry struct {
+	Seq        int64                  `json:"seq"`
+	Timestamp  time.Time              `json:"timestamp"`
+	ActorID    string                 `json:"actor_id,omitempty"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Changes    map[string]AuditChange `json:"changes,omitempty"`
+	IP         string                 `json:"ip,omitempty"`
+	PrevHash   string                 `json:"prev_hash"`
+	Hash       string                 `json:"hash"`
+}
+
+// AuditChange holds the JSON encoded value of a field before and after a
+// mutation. Before is empty for created records and After for removed ones.
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditLog stores audit entries. Implementations are expected to chain
+// entries with LinkAuditEntry so that VerifyChain can detect tampering.
+type AuditLog interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	// Entries returns every entry in sequence order.
+	Entries(ctx context.Context) ([]AuditEntry, error)
+	// Query returns the entries matching q in sequence order, starting after
+	// q.Cursor. See FilterAuditEntries for the reference semantics.
+	Query(ctx context.Context, q AuditQuery) (*AuditPage, error)
+}
+
+// ComputeHash returns the hex SHA-256 of every field except Hash.
+func (e AuditEntry) ComputeHash() string {
+	e.Hash = ""
+	e.Timestamp = e.Timestamp.UTC()
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// LinkAuditEntry assigns entry the sequence number following prev, points it
+// at prev's hash and seals it with its own hash. prev is nil for the first
+// entry of a log.
+func LinkAuditEntry(prev *AuditEntry, entry AuditEntry) AuditEntry {
+	entry.Seq = 1
+	entry.PrevHash = ""
+	if prev != nil {
+		entry.Seq = prev.Seq + 1
+		entry.PrevHash = prev.Hash
+	}
+	entry.Hash = entry.ComputeHash()
+	return entry
+}
+
+// AuditChainError describes the first entry at which VerifyChain found the
+// chain broken. It matches ErrAuditChainBroken with errors.Is.
+type AuditChainError struct {
+	Seq    int64
+	Reason string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at entry %d: %s", e.Seq, e.Reason)
+}
+
+func (e *AuditChainError) Unwrap() error {
+	return ErrAuditChainBroken
+}
+
+// VerifyChain checks that entries form an unbroken hash chain: every entry's
+// hash matches its contents and points at its predecessor, and no sequence
+// numbers are missing. Removing entries from the end of the log cannot be
+// detected from the entries alone; compare the last hash with one kept
+// elsewhere for that.
+func VerifyChain(entries []AuditEntry) error {
+	for i, entry := range entries {
+		if entry.Hash != entry.ComputeHash() {
+			return &AuditChainError{Seq: entry.Seq, Reason: "hash does not match contents"}
+		}
+		if i == 0 {
+			if entry.Seq == 1 && entry.PrevHash != "" {
+				return &AuditChainError{Seq: entry.Seq, Reason: "first entry has a predecessor"}
+			}
+			continue
+		}
+		prev := entries[i-1]
+		if entry.Seq != prev.Seq+1 {
+			return &AuditChainError{Seq: entry.Seq, Reason: fmt.Sprintf("expected sequence %d", prev.Seq+1)}
+		}
+		if entry.PrevHash != prev.Hash {
+			return &AuditChainError{Seq: entry.Seq, Reason: "previous hash does not match"}
+		}
+	}
+	return nil
+}
+
+// MemoryAuditLog is a hash-chained AuditLog kept in process memory.
+type MemoryAuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+// Record links entry into the chain right away, or, inside a memory
+// transaction, once that commits. Holding entries back keeps the chain intact
+// without undoing records made outside the transaction meanwhile.
+func (m *MemoryAuditLog) Record(ctx context.Context, entry AuditEntry) error {
+	if onCommit(ctx, func() { m.link(entry) }) {
+		return nil
+	}
+	m.link(entry)
+	return nil
+}
+
+func (m *MemoryAuditLog) link(entry AuditEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var prev *AuditEntry
+	if len(m.entries) > 0 {
+		prev = &m.entries[len(m.entries)-1]
+	}
+	m.entries = append(m.entries, LinkAuditEntry(prev, entry))
+}
+
+func (m *MemoryAuditLog) Entries(ctx context.Context) ([]AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AuditEntry(nil), m.entries...), nil
+}
+
+func (m *MemoryAuditLog) Query(ctx context.Context, q AuditQuery) (*AuditPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return FilterAuditEntries(m.entries, q)
+}
+
+func WithAuditLog(log AuditLog) ServiceOption {
+	return func(s *Service) {
+		s.auditLog = log
+	}
+}
+
+// audit records action on the target together with the field-level diff
+// between before and after, either of which may be nil. Failing to record is
+// returned so the surrounding transaction can roll back.
+func (s *Service) audit(ctx context.Context, action, targetType, targetID string, before, after any) error {
+	if s.auditLog == nil {
+		return nil
+	}
+
+	actor, _ := ActorFromContext(ctx)
+	ip, _ := environmentFromContext(ctx, s.now())[EnvironmentClientIP].(string)
+	err := s.auditLog.Record(ctx, AuditEntry{
+		Timestamp:  s.now(),
+		ActorID:    actor.UserID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    auditChanges(before, after),
+		IP:         ip,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToWriteAudit, err)
+	}
+	return nil
+}
+
+// auditChanges compares the exported fields of two values of the same struct
+// type and returns the ones that differ.
+func auditChanges(before, after any) map[string]AuditChange {
+	bv, av := auditStruct(before), auditStruct(after)
+	var typ reflect.Type
+	switch {
+	case av.IsValid():
+		typ = av.Type()
+	case bv.IsValid():
+		typ = bv.Type()
+	default:
+		return nil
+	}
+
+	changes := map[string]AuditChange{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		var b, a reflect.Value
+		if bv.IsValid() {
+			b = bv.Field(i)
+		}
+		if av.IsValid() {
+			a = av.Field(i)
+		}
+		if b.IsValid() && a.IsValid() && reflect.DeepEqual(b.Interface(), a.Interface()) {
+			continue
+		}
+
+		change := AuditChange{
+			Before: auditValue(field.Name, b),
+			After:  auditValue(field.Name, a),
+		}
+		if change.Before != nil || change.After != nil {
+			changes[field.Name] = change
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func auditStruct(v any) reflect.Value {
+	if v == nil {
+		return reflect.Value{}
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return rv
+}
+
+func auditValue(name string, v reflect.Value) json.RawMessage {
+	if !v.IsValid() || v.IsZero() {
+		return nil
+	}
+	if redactedAuditFields[name] {
+		return redactedAuditValue
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,213 @@
+package users
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const PermissionAuditRead = "audit:read"
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 1000
+)
+
+// AuditQuery selects audit entries. Zero-valued fields do not filter; From is
+// inclusive and To exclusive.
+type AuditQuery struct {
+	ActorID    string
+	TargetType string
+	TargetID   string
+	Actions    []string
+	From       time.Time
+	To         time.Time
+	Cursor     string
+	Limit      int
+}
+
+// AuditPage is one page of query results. NextCursor is empty on the last
+// page.
+type AuditPage struct {
+	Entries    []AuditEntry
+	NextCursor string
+}
+
+type AuditExportFormat string
+
+const (
+	AuditExportJSONL AuditExportFormat = "jsonl"
+	AuditExportCSV   AuditExportFormat = "csv"
+)
+
+func (q AuditQuery) matches(entry AuditEntry) bool {
+	switch {
+	case q.ActorID != "" && entry.ActorID != q.ActorID:
+		return false
+	case q.TargetType != "" && entry.TargetType != q.TargetType:
+		return false
+	case q.TargetID != "" && entry.TargetID != q.TargetID:
+		return false
+	case len(q.Actions) > 0 && !slices.Contains(q.Actions, entry.Action):
+		return false
+	case !q.From.IsZero() && entry.Timestamp.Before(q.From):
+		return false
+	case !q.To.IsZero() && !entry.Timestamp.Before(q.To):
+		return false
+	}
+	return true
+}
+
+// FilterAuditEntries applies q to entries, which must be in sequence order.
+// AuditLog implementations backed by a database should produce the same
+// pages and cursors.
+func FilterAuditEntries(entries []AuditEntry, q AuditQuery) (*AuditPage, error) {
+	after, err := decodeAuditCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := auditPageSize(q.Limit)
+
+	page := &AuditPage{Entries: []AuditEntry{}}
+	for _, entry := range entries {
+		if entry.Seq <= after || !q.matches(entry) {
+			continue
+		}
+		if len(page.Entries) == limit {
+			page.NextCursor = encodeCursor(strconv.FormatInt(page.Entries[limit-1].Seq, 10))
+			break
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	return page, nil
+}
+
+func auditPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultAuditPageSize
+	}
+	return min(limit, MaxAuditPageSize)
+}
+
+// Cursors are opaque to callers; they wrap the position they resume after.
+func encodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	return string(position), nil
+}
+
+func decodeAuditCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	position, err := decodeCursor(cursor)
+	if err != nil {
+		return 0, err
+	}
+	seq, err := strconv.ParseInt(position, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return seq, nil
+}
+
+func (s *Service) QueryAuditLog(ctx context.Context, q AuditQuery) (*AuditPage, error) {
+	if s.auditLog == nil {
+		return nil, ErrAuditLogNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionAuditRead, Resource{Type: "audit"}); err != nil {
+		return nil, err
+	}
+	return s.auditLog.Query(ctx, q)
+}
+
+// ExportAuditLog writes every entry matching q to w, following cursors from
+// q.Cursor to the end of the log. Entries keep their hashes so auditors can
+// run VerifyChain on a complete export.
+func (s *Service) ExportAuditLog(ctx context.Context, q AuditQuery, format AuditExportFormat, w io.Writer) error {
+	var write func(io.Writer, []AuditEntry) error
+	switch format {
+	case AuditExportJSONL:
+		write = WriteAuditJSONL
+	case AuditExportCSV:
+		write = WriteAuditCSV
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedExportFormat, format)
+	}
+
+	var entries []AuditEntry
+	q.Limit = MaxAuditPageSize
+	for {
+		page, err := s.QueryAuditLog(ctx, q)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, page.Entries...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	return write(w, entries)
+}
+
+// WriteAuditJSONL writes one JSON object per line.
+func WriteAuditJSONL(w io.Writer, entries []AuditEntry) error {
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var auditCSVHeader = []string{"seq", "timestamp", "actor_id", "action", "target_type", "target_id", "ip", "changes", "prev_hash", "hash"}
+
+// WriteAuditCSV writes a header row followed by one row per entry. Changes
+// are embedded as a JSON object.
+func WriteAuditCSV(w io.Writer, entries []AuditEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(auditCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		changes := ""
+		if len(entry.Changes) > 0 {
+			data, err := json.Marshal(entry.Changes)
+			if err != nil {
+				return err
+			}
+			changes = string(data)
+		}
+		err := cw.Write([]string{
+			strconv.FormatInt(entry.Seq, 10),
+			entry.Timestamp.UTC().Format(time.RFC3339Nano),
+			entry.ActorID,
+			entry.Action,
+			entry.TargetType,
+			entry.TargetID,
+			entry.IP,
+			changes,
+			entry.PrevHash,
+			entry.Hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
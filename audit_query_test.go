@@ -0,0 +1,134 @@
+package users
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newAuditedService(t *testing.T) (*Service, *MemoryAuditLog, time.Time) {
+	t.Helper()
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	clock := &testClock{now: start}
+	svc, _ := newEnforcingService()
+	auditLog := NewMemoryAuditLog()
+	svc.auditLog = auditLog
+	svc.now = clock.Now
+
+	// One hour apart: reset alice, reset bob, assign alice, suspend bob.
+	steps := []func() error{
+		func() error { _, err := svc.ResetPassword(actorCtx("admin"), "alice", "x"); return err },
+		func() error { _, err := svc.ResetPassword(actorCtx("admin"), "bob", "x"); return err },
+		func() error { _, err := svc.AssignRoleToUser(actorCtx("admin"), "alice", "r-mod"); return err },
+		func() error { _, err := svc.SuspendUser(actorCtx("admin"), "bob", "spam", nil); return err },
+	}
+	for _, step := range steps {
+		require.NoError(t, step())
+		clock.now = clock.now.Add(time.Hour)
+	}
+	return svc, auditLog, start
+}
+
+func TestQueryAuditLog(t *testing.T) {
+	svc, _, start := newAuditedService(t)
+	ctx := actorCtx("admin")
+
+	t.Run("filters", func(t *testing.T) {
+		page, err := svc.QueryAuditLog(ctx, AuditQuery{TargetType: "user", TargetID: "alice"})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 2)
+		require.Empty(t, page.NextCursor)
+
+		page, err = svc.QueryAuditLog(ctx, AuditQuery{Actions: []string{AuditUserStatusChanged, AuditRoleAssigned}})
+		require.NoError(t, err)
+		require.Equal(t, []int64{3, 4}, auditSeqs(page.Entries))
+
+		page, err = svc.QueryAuditLog(ctx, AuditQuery{From: start.Add(time.Hour), To: start.Add(3 * time.Hour)})
+		require.NoError(t, err)
+		require.Equal(t, []int64{2, 3}, auditSeqs(page.Entries))
+
+		page, err = svc.QueryAuditLog(ctx, AuditQuery{ActorID: "mod"})
+		require.NoError(t, err)
+		require.Empty(t, page.Entries)
+	})
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		var seqs []int64
+		q := AuditQuery{Limit: 3}
+		for {
+			page, err := svc.QueryAuditLog(ctx, q)
+			require.NoError(t, err)
+			seqs = append(seqs, auditSeqs(page.Entries)...)
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+		require.Equal(t, []int64{1, 2, 3, 4}, seqs)
+
+		_, err := svc.QueryAuditLog(ctx, AuditQuery{Cursor: "!!"})
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("requires permission", func(t *testing.T) {
+		_, err := svc.QueryAuditLog(actorCtx("alice"), AuditQuery{})
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		svc := NewService(&mockUserRepo{}, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.QueryAuditLog(context.Background(), AuditQuery{})
+		require.ErrorIs(t, err, ErrAuditLogNotConfigured)
+	})
+}
+
+func TestExportAuditLog(t *testing.T) {
+	svc, _, _ := newAuditedService(t)
+	ctx := actorCtx("admin")
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, svc.ExportAuditLog(ctx, AuditQuery{}, AuditExportJSONL, &buf))
+
+		var entries []AuditEntry
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var entry AuditEntry
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			entries = append(entries, entry)
+		}
+		require.Len(t, entries, 4)
+		require.NoError(t, VerifyChain(entries))
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, svc.ExportAuditLog(ctx, AuditQuery{TargetID: "bob"}, AuditExportCSV, &buf))
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		require.Equal(t, auditCSVHeader, rows[0])
+		require.Equal(t, []string{"2", "2025-10-01T01:00:00Z", "admin", AuditPasswordReset, "user", "bob"}, rows[1][:6])
+		require.JSONEq(t, `{"HashedPassword":{"before":"[redacted]","after":"[redacted]"}}`, rows[1][7])
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		err := svc.ExportAuditLog(ctx, AuditQuery{}, "xml", &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUnsupportedExportFormat)
+	})
+}
+
+func auditSeqs(entries []AuditEntry) []int64 {
+	var seqs []int64
+	for _, entry := range entries {
+		seqs = append(seqs, entry.Seq)
+	}
+	return seqs
+}
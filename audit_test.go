@@ -0,0 +1,165 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingAuditLog struct {
+	MemoryAuditLog
+}
+
+func (f *failingAuditLog) Record(ctx context.Context, entry AuditEntry) error {
+	return errors.New("unavailable")
+}
+
+func TestServiceAuditsMutations(t *testing.T) {
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	svc, _ := newEnforcingService()
+	auditLog := NewMemoryAuditLog()
+	svc.auditLog = auditLog
+	svc.now = func() time.Time { return now }
+	ctx := ContextWithEnvironment(actorCtx("admin"), map[string]any{EnvironmentClientIP: "203.0.113.7"})
+
+	_, err := svc.ResetPassword(ctx, "alice", "new")
+	require.NoError(t, err)
+	_, err = svc.AssignRoleToUser(ctx, "alice", "r-admin")
+	require.NoError(t, err)
+
+	entries, err := auditLog.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	reset := entries[0]
+	require.Equal(t, AuditPasswordReset, reset.Action)
+	require.Equal(t, "admin", reset.ActorID)
+	require.Equal(t, "user", reset.TargetType)
+	require.Equal(t, "alice", reset.TargetID)
+	require.Equal(t, "203.0.113.7", reset.IP)
+	require.Equal(t, now, reset.Timestamp)
+	require.Equal(t, map[string]AuditChange{
+		"HashedPassword": {Before: redactedAuditValue, After: redactedAuditValue},
+	}, reset.Changes)
+
+	assigned := entries[1]
+	require.Equal(t, AuditRoleAssigned, assigned.Action)
+	require.JSONEq(t, `"r-user"`, string(assigned.Changes["RoleID"].Before))
+	require.JSONEq(t, `"r-admin"`, string(assigned.Changes["RoleID"].After))
+	require.Equal(t, reset.Hash, assigned.PrevHash)
+
+	require.NoError(t, VerifyChain(entries))
+
+	t.Run("audit failure fails the mutation", func(t *testing.T) {
+		svc.auditLog = &failingAuditLog{}
+		defer func() { svc.auditLog = auditLog }()
+
+		_, err := svc.ResetPassword(ctx, "bob", "new")
+		require.ErrorIs(t, err, ErrFailedToWriteAudit)
+	})
+}
+
+func TestAuditChanges(t *testing.T) {
+	deletedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := User{ID: "u1", Email: "a@example.com", RoleID: "r-user"}
+	after := User{ID: "u1", Email: "b@example.com", RoleID: "r-admin", DeletedAt: &deletedAt}
+
+	changes := auditChanges(before, &after)
+	require.Len(t, changes, 3)
+	require.JSONEq(t, `"r-user"`, string(changes["RoleID"].Before))
+	require.JSONEq(t, `"r-admin"`, string(changes["RoleID"].After))
+	require.Equal(t, AuditChange{Before: redactedAuditValue, After: redactedAuditValue}, changes["Email"], "personal data")
+	require.Nil(t, changes["DeletedAt"].Before)
+	require.JSONEq(t, `"2025-01-01T00:00:00Z"`, string(changes["DeletedAt"].After))
+
+	created := auditChanges(nil, &before)
+	require.Len(t, created, 3)
+	require.Nil(t, created["ID"].Before)
+
+	require.Nil(t, auditChanges(nil, nil))
+	require.Nil(t, auditChanges(before, before))
+}
+
+func TestVerifyChain(t *testing.T) {
+	ctx := context.Background()
+	newLog := func() []AuditEntry {
+		log := NewMemoryAuditLog()
+		for _, target := range []string{"alice", "bob", "carol"} {
+			require.NoError(t, log.Record(ctx, AuditEntry{
+				Action:   AuditUserDeleted,
+				TargetID: target,
+				Changes:  map[string]AuditChange{"Email": {Before: json.RawMessage(`"` + target + `@example.com"`)}},
+			}))
+		}
+		entries, err := log.Entries(ctx)
+		require.NoError(t, err)
+		return entries
+	}
+
+	require.NoError(t, VerifyChain(newLog()))
+	require.NoError(t, VerifyChain(newLog()[1:]), "a suffix of the log verifies on its own")
+
+	t.Run("survives a JSON round trip", func(t *testing.T) {
+		data, err := json.Marshal(newLog())
+		require.NoError(t, err)
+		var decoded []AuditEntry
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.NoError(t, VerifyChain(decoded))
+	})
+
+	t.Run("edited entry", func(t *testing.T) {
+		entries := newLog()
+		entries[1].ActorID = "mallory"
+		var chainErr *AuditChainError
+		err := VerifyChain(entries)
+		require.ErrorIs(t, err, ErrAuditChainBroken)
+		require.True(t, errors.As(err, &chainErr))
+		require.Equal(t, int64(2), chainErr.Seq)
+	})
+
+	t.Run("edited and rehashed entry", func(t *testing.T) {
+		entries := newLog()
+		entries[1].TargetID = "mallory"
+		entries[1].Hash = entries[1].ComputeHash()
+		err := VerifyChain(entries)
+		var chainErr *AuditChainError
+		require.True(t, errors.As(err, &chainErr))
+		require.Equal(t, int64(3), chainErr.Seq)
+	})
+
+	t.Run("deleted entry", func(t *testing.T) {
+		entries := newLog()
+		entries = append(entries[:1], entries[2:]...)
+		require.ErrorIs(t, VerifyChain(entries), ErrAuditChainBroken)
+	})
+}
+
+func TestMemoryAuditLogTransactions(t *testing.T) {
+	ctx := context.Background()
+	txManager := NewMemoryTxManager()
+	log := NewMemoryAuditLog()
+
+	failure := errors.New("boom")
+	err := txManager.WithinTx(ctx, func(txCtx context.Context) error {
+		require.NoError(t, log.Record(txCtx, AuditEntry{Action: AuditUserDeleted, TargetID: "rolled-back"}))
+		// Recorded outside the transaction while it is still open.
+		require.NoError(t, log.Record(ctx, AuditEntry{Action: AuditUserDeleted, TargetID: "outside"}))
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+
+	require.NoError(t, txManager.WithinTx(ctx, func(txCtx context.Context) error {
+		return log.Record(txCtx, AuditEntry{Action: AuditUserDeleted, TargetID: "committed"})
+	}))
+
+	entries, err := log.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "outside", entries[0].TargetID)
+	require.Equal(t, "committed", entries[1].TargetID)
+	require.NoError(t, VerifyChain(entries))
+}
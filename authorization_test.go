@@ -0,0 +1,140 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newEnforcingService() (*Service, *mockUserRepo) {
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"admin": {ID: "admin", Email: "admin@example.com", RoleID: "r-admin", HashedPassword: "hashed:admin"},
+		"mod":   {ID: "mod", Email: "mod@example.com", RoleID: "r-mod", HashedPassword: "hashed:mod"},
+		"alice": {ID: "alice", Email: "alice@example.com", RoleID: "r-user", HashedPassword: "hashed:alice"},
+		"bob":   {ID: "bob", Email: "bob@example.com", RoleID: "r-user", HashedPassword: "hashed:bob"},
+	}}
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{
+		"r-admin": {ID: "r-admin", Name: RoleAdmin},
+		"r-mod":   {ID: "r-mod", Name: RoleModerator, ParentIDs: []string{"r-user"}, Permissions: []string{"users:read", "users:list"}},
+		"r-user":  {ID: "r-user", Name: RoleUser},
+	}}
+	return NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithAuthorizationEnforcement()), userRepo
+}
+
+func actorCtx(userID string) context.Context {
+	return ContextWithActor(context.Background(), Actor{UserID: userID})
+}
+
+func TestAuthorizationEnforcement(t *testing.T) {
+	svc, userRepo := newEnforcingService()
+
+	t.Run("no actor", func(t *testing.T) {
+		_, err := svc.ListUsers(context.Background())
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("unknown actor", func(t *testing.T) {
+		_, err := svc.ListUsers(actorCtx("ghost"))
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("admin can do anything", func(t *testing.T) {
+		_, err := svc.AssignRoleToUser(actorCtx("admin"), "bob", "r-mod")
+		require.NoError(t, err)
+		_, err = svc.ResetPassword(actorCtx("admin"), "bob", "newpw")
+		require.NoError(t, err)
+		userRepo.users["bob"].RoleID = "r-user"
+	})
+
+	t.Run("permission from role", func(t *testing.T) {
+		us, err := svc.ListUsers(actorCtx("mod"))
+		require.NoError(t, err)
+		require.Len(t, us, 4)
+	})
+
+	t.Run("missing permission", func(t *testing.T) {
+		err := svc.DeleteUser(actorCtx("mod"), "alice")
+		require.ErrorIs(t, err, ErrForbidden)
+		require.Contains(t, userRepo.users, "alice")
+
+		_, err = svc.ResetPassword(actorCtx("alice"), "bob", "pwned")
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("self service", func(t *testing.T) {
+		u, err := svc.GetUserByID(actorCtx("alice"), "alice")
+		require.NoError(t, err)
+		require.Equal(t, "alice", u.ID)
+
+		_, err = svc.ChangePassword(actorCtx("alice"), "alice", "alice", "newalice")
+		require.NoError(t, err)
+
+		_, err = svc.GetUserByID(actorCtx("alice"), "bob")
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("self update cannot escalate role", func(t *testing.T) {
+		alice := *userRepo.users["alice"]
+		alice.RoleID = "r-admin"
+		_, err := svc.UpdateUser(actorCtx("alice"), alice)
+		require.ErrorIs(t, err, ErrForbidden)
+		require.Equal(t, "r-user", userRepo.users["alice"].RoleID)
+	})
+
+	t.Run("self update cannot change attributes", func(t *testing.T) {
+		alice := *userRepo.users["alice"]
+		alice.Attributes = map[string]string{"region": "eu", "owner_id": "bob"}
+		_, err := svc.UpdateUser(actorCtx("alice"), alice)
+		require.ErrorIs(t, err, ErrForbidden)
+		require.Empty(t, userRepo.users["alice"].Attributes)
+
+		resource := UserResource(alice)
+		require.Equal(t, "alice", resource.Attributes["owner_id"])
+	})
+
+	t.Run("self update cannot mark erased", func(t *testing.T) {
+		alice := *userRepo.users["alice"]
+		erasedAt := time.Now()
+		alice.ErasedAt = &erasedAt
+		_, err := svc.UpdateUser(actorCtx("alice"), alice)
+		require.NoError(t, err)
+		require.Nil(t, userRepo.users["alice"].ErasedAt)
+	})
+
+	t.Run("system actor", func(t *testing.T) {
+		_, err := svc.ListUsers(ContextWithSystemActor(context.Background()))
+		require.NoError(t, err)
+	})
+
+	t.Run("register stays open", func(t *testing.T) {
+		_, err := svc.Register(context.Background(), UserRegisterInput{Email: "new@example.com", Username: "new", Password: "pw"})
+		require.NoError(t, err)
+	})
+}
+
+func TestAuthorizationEnforcementWithPolicy(t *testing.T) {
+	_, userRepo := newEnforcingService()
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{"r-user": {ID: "r-user", Name: RoleUser}}}
+	policy := Policy{Rules: []PolicyRule{{
+		ID:         "same-region-read",
+		Effect:     PolicyAllow,
+		Actions:    []string{PermissionUsersRead},
+		Resources:  []string{"user"},
+		Conditions: []PolicyCondition{{Attribute: "resource.region", Operator: OpEquals, ValueFrom: "subject.region"}},
+	}}}
+	engine, err := NewPolicyEngine(policy)
+	require.NoError(t, err)
+	svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithAuthorizationEnforcement(), WithPolicyEngine(engine))
+
+	userRepo.users["alice"].Attributes = map[string]string{"region": "eu"}
+	userRepo.users["bob"].Attributes = map[string]string{"region": "eu"}
+	userRepo.users["mod"].Attributes = map[string]string{"region": "us"}
+
+	_, err = svc.GetUserByID(actorCtx("alice"), "bob")
+	require.NoError(t, err)
+
+	_, err = svc.GetUserByID(actorCtx("mod"), "bob")
+	require.ErrorIs(t, err, ErrForbidden)
+}
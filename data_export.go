@@ -0,0 +1,171 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const PermissionUsersExport = "users:export"
+
+// UserDataExporter lets other modules add their own section, such as
+// sessions or consents, to a user's data export.
+type UserDataExporter interface {
+	Section() string
+	ExportUserData(ctx context.Context, user User) (any, error)
+}
+
+// UserDataExport is the archive produced by ExportUserData. Credentials are
+// never included.
+type UserDataExport struct {
+	FormatVersion int                  `json:"format_version"`
+	ExportedAt    time.Time            `json:"exported_at"`
+	Profile       ExportedProfile      `json:"profile"`
+	Roles         []ExportedRole       `json:"roles"`
+	Memberships   []ExportedMembership `json:"organization_memberships,omitempty"`
+	AuditEvents   []ExportedAuditEvent `json:"audit_events,omitempty"`
+	Sections      map[string]any       `json:"sections,omitempty"`
+}
+
+type ExportedProfile struct {
+	ID              string            `json:"id"`
+	Email           string            `json:"email"`
+	Username        string            `json:"username"`
+	DisplayName     string            `json:"display_name,omitempty"`
+	Attributes      map[string]string `json:"attributes,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	LastSeen        time.Time         `json:"last_seen"`
+	Status          UserStatus        `json:"status,omitempty"`
+	StatusReason    string            `json:"status_reason,omitempty"`
+	StatusExpiresAt *time.Time        `json:"status_expires_at,omitempty"`
+	DeletedAt       *time.Time        `json:"deleted_at,omitempty"`
+}
+
+type ExportedRole struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+type ExportedMembership struct {
+	OrganizationID string    `json:"organization_id"`
+	RoleID         string    `json:"role_id"`
+	JoinedAt       time.Time `json:"joined_at"`
+}
+
+// ExportedAuditEvent is an audit log entry about the user, without the
+// fields that only serve the hash chain.
+type ExportedAuditEvent struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Action    string                 `json:"action"`
+	ActorID   string                 `json:"actor_id,omitempty"`
+	Changes   map[string]AuditChange `json:"changes,omitempty"`
+}
+
+func WithUserDataExporters(exporters ...UserDataExporter) ServiceOption {
+	return func(s *Service) {
+		s.exporters = append(s.exporters, exporters...)
+	}
+}
+
+// ExportUserData assembles everything known about a user into a JSON archive
+// suitable for answering a data subject access request.
+func (s *Service) ExportUserData(ctx context.Context, userID string) ([]byte, error) {
+	if err := s.authorizeUser(ctx, PermissionUsersExport, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	export := UserDataExport{
+		FormatVersion: 1,
+		ExportedAt:    s.now(),
+		Profile: ExportedProfile{
+			ID:              user.ID,
+			Email:           user.Email,
+			Username:        user.Username,
+			DisplayName:     user.DisplayName,
+			Attributes:      user.Attributes,
+			CreatedAt:       user.CreatedAt,
+			LastSeen:        user.LastSeen,
+			Status:          user.Status,
+			StatusReason:    user.StatusReason,
+			StatusExpiresAt: user.StatusExpiresAt,
+			DeletedAt:       user.DeletedAt,
+		},
+		Roles: []ExportedRole{},
+	}
+
+	roleIDs, err := s.effectiveRoleIDs(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToExportUserData, err)
+	}
+	for _, roleID := range roleIDs {
+		tree, err := s.resolveRoleTree(ctx, roleID, map[string]bool{})
+		if err != nil {
+			continue
+		}
+		export.Roles = append(export.Roles, ExportedRole{
+			ID:          tree.Role.ID,
+			Name:        tree.Role.Name,
+			Permissions: tree.EffectivePermissions(),
+		})
+	}
+
+	if s.orgRepo != nil {
+		memberships, err := s.orgRepo.ListUserMemberships(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToExportUserData, err)
+		}
+		for _, m := range memberships {
+			export.Memberships = append(export.Memberships, ExportedMembership{
+				OrganizationID: m.OrganizationID,
+				RoleID:         m.RoleID,
+				JoinedAt:       m.JoinedAt,
+			})
+		}
+	}
+
+	if s.auditLog != nil {
+		query := AuditQuery{TargetType: "user", TargetID: user.ID}
+		for {
+			page, err := s.auditLog.Query(ctx, query)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrFailedToExportUserData, err)
+			}
+			for _, entry := range page.Entries {
+				export.AuditEvents = append(export.AuditEvents, ExportedAuditEvent{
+					Timestamp: entry.Timestamp,
+					Action:    entry.Action,
+					ActorID:   entry.ActorID,
+					Changes:   entry.Changes,
+				})
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+	}
+
+	for _, exporter := range s.exporters {
+		section, err := exporter.ExportUserData(ctx, *user)
+		if err != nil {
+			return nil, fmt.Errorf("%w: section %s: %v", ErrFailedToExportUserData, exporter.Section(), err)
+		}
+		if export.Sections == nil {
+			export.Sections = map[string]any{}
+		}
+		export.Sections[exporter.Section()] = section
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToExportUserData, err)
+	}
+	return data, nil
+}
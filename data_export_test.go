@@ -0,0 +1,102 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sessionExporter struct {
+	err error
+}
+
+func (e sessionExporter) Section() string { return "sessions" }
+
+func (e sessionExporter) ExportUserData(ctx context.Context, user User) (any, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return []map[string]string{{"id": "s1", "user_id": user.ID, "ip": "192.0.2.1"}}, nil
+}
+
+func TestExportUserData(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"alice": {ID: "alice", Email: "alice@example.com", Username: "alice", HashedPassword: "hashed:secret", RoleID: "r-mod", Attributes: map[string]string{"region": "eu"}},
+	}}
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{
+		"r-user": {ID: "r-user", Name: RoleUser, Permissions: []string{"profile:read"}},
+		"r-mod":  {ID: "r-mod", Name: RoleModerator, ParentIDs: []string{"r-user"}, Permissions: []string{"users:suspend"}},
+	}}
+	orgRepo := newMockOrganizationRepo()
+	orgRepo.memberships[membershipKey("acme", "alice")] = &Membership{OrganizationID: "acme", UserID: "alice", RoleID: "r-user", JoinedAt: now}
+
+	svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{},
+		WithClock(func() time.Time { return now }),
+		WithOrganizationRepository(orgRepo),
+		WithUserDataExporters(sessionExporter{}))
+
+	t.Run("success", func(t *testing.T) {
+		data, err := svc.ExportUserData(ctx, "alice")
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "hashed:secret")
+
+		var export UserDataExport
+		require.NoError(t, json.Unmarshal(data, &export))
+		require.Equal(t, 1, export.FormatVersion)
+		require.Equal(t, now, export.ExportedAt)
+		require.Equal(t, "alice@example.com", export.Profile.Email)
+		require.Equal(t, "eu", export.Profile.Attributes["region"])
+		require.Equal(t, []ExportedRole{{ID: "r-mod", Name: RoleModerator, Permissions: []string{"profile:read", "users:suspend"}}}, export.Roles)
+		require.Equal(t, []ExportedMembership{{OrganizationID: "acme", RoleID: "r-user", JoinedAt: now}}, export.Memberships)
+		require.Contains(t, export.Sections, "sessions")
+	})
+
+	t.Run("audit events", func(t *testing.T) {
+		auditLog := NewMemoryAuditLog()
+		svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithAuditLog(auditLog))
+		for _, entry := range []AuditEntry{
+			{Action: AuditRoleAssigned, ActorID: "admin", TargetType: "user", TargetID: "alice"},
+			{Action: AuditRoleAssigned, ActorID: "admin", TargetType: "user", TargetID: "bob"},
+			{Action: AuditUserUpdated, ActorID: "alice", TargetType: "user", TargetID: "alice"},
+		} {
+			require.NoError(t, auditLog.Record(ctx, entry))
+		}
+
+		data, err := svc.ExportUserData(ctx, "alice")
+		require.NoError(t, err)
+		var export UserDataExport
+		require.NoError(t, json.Unmarshal(data, &export))
+		require.Len(t, export.AuditEvents, 2)
+		require.Equal(t, AuditRoleAssigned, export.AuditEvents[0].Action)
+		require.Equal(t, "admin", export.AuditEvents[0].ActorID)
+		require.Equal(t, AuditUserUpdated, export.AuditEvents[1].Action)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		_, err := svc.ExportUserData(ctx, "ghost")
+		require.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("exporter fails", func(t *testing.T) {
+		svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithUserDataExporters(sessionExporter{err: errors.New("fail")}))
+		_, err := svc.ExportUserData(ctx, "alice")
+		require.ErrorIs(t, err, ErrFailedToExportUserData)
+	})
+
+	t.Run("users may export their own data", func(t *testing.T) {
+		svc.enforceAuthz = true
+		defer func() { svc.enforceAuthz = false }()
+		userRepo.users["bob"] = &User{ID: "bob"}
+
+		_, err := svc.ExportUserData(actorCtx("alice"), "alice")
+		require.NoError(t, err)
+		_, err = svc.ExportUserData(actorCtx("bob"), "alice")
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+}
@@ -0,0 +1,238 @@
+package users
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	MaxEmailLength      = 254
+	MaxEmailLocalLength = 64
+)
+
+// EmailPolicy decides which email addresses Register and user updates
+// accept, and when two addresses count as the same account.
+type EmailPolicy struct {
+	// CanonicalizeProviders treats addresses that a known provider delivers
+	// to one mailbox as the same address, such as "j.doe+news@gmail.com" and
+	// "jdoe@gmail.com". Addresses are still stored in normalized, not
+	// canonical, form.
+	CanonicalizeProviders bool
+	// BlockedDomains rejects addresses at these domains and their
+	// subdomains.
+	BlockedDomains []string
+}
+
+// DefaultEmailPolicy blocks DefaultDisposableDomains and leaves provider
+// canonicalization off.
+func DefaultEmailPolicy() EmailPolicy {
+	return EmailPolicy{BlockedDomains: DefaultDisposableDomains}
+}
+
+// DefaultDisposableDomains lists widely used throwaway mail services. Larger
+// lists can be loaded with LoadDomainListFile.
+var DefaultDisposableDomains = []string{
+	"10minutemail.com", "dispostable.com", "fakeinbox.com", "getnada.com",
+	"guerrillamail.com", "mailinator.com", "maildrop.cc", "sharklasers.com",
+	"temp-mail.org", "throwawaymail.com", "trashmail.com", "yopmail.com",
+}
+
+func WithEmailPolicy(policy EmailPolicy) ServiceOption {
+	return func(s *Service) {
+		s.emailPolicy = policy
+	}
+}
+
+// ParseDomainList reads one domain per line, skipping blank lines and
+// comments starting with "#".
+func ParseDomainList(data []byte) []string {
+	var domains []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if domain := strings.ToLower(strings.TrimSpace(line)); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// LoadDomainListFile reads a domain list such as a disposable-domain
+// blocklist from disk.
+func LoadDomainListFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDomainList(data), nil
+}
+
+// NormalizeEmail checks that email is a single RFC 5322 addr-spec and returns
+// it lowercased, with an internationalized domain converted to its ASCII
+// (punycode) form. The local part is lowercased too: nearly every provider
+// ignores its case, and keeping it would let "Bob@" and "bob@" register as
+// two accounts. Addresses stored before normalization was introduced should
+// be rewritten with NormalizeEmail; until then Login also tries the address
+// exactly as typed, but uniqueness checks only see normalized ones.
+func NormalizeEmail(email string) (string, error) {
+	invalid := func(code FieldCode, message string) error {
+		return invalidField("email", code, ErrInvalidEmail, message)
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", invalid(CodeRequired, "is required")
+	}
+	if len(email) > MaxEmailLength {
+		return "", invalid(CodeTooLong, fmt.Sprintf("must be at most %d bytes", MaxEmailLength))
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return "", invalid(CodeInvalidFormat, "must contain @")
+	}
+	local, domain := email[:at], email[at+1:]
+	if len(local) > MaxEmailLocalLength {
+		return "", invalid(CodeTooLong, fmt.Sprintf("local part must be at most %d bytes", MaxEmailLocalLength))
+	}
+
+	domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", invalid(CodeInvalidFormat, "has an invalid domain")
+	}
+
+	// net/mail implements the RFC 5322 grammar, but display names,
+	// comments and quoted local parts are rejected: they have no place in a
+	// stored address and would make equal addresses compare unequal.
+	addr := strings.ToLower(local) + "@" + domain
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil || parsed.Name != "" || parsed.Address != addr {
+		return "", invalid(CodeInvalidFormat, "is not a valid address")
+	}
+	return addr, nil
+}
+
+// Validate checks a normalized address against the policy. Problems are
+// reported as a ValidationError on the "email" field.
+func (p EmailPolicy) Validate(email string) error {
+	domain := emailDomain(email)
+	blocked := slices.ContainsFunc(p.BlockedDomains, func(blocked string) bool {
+		blocked = strings.ToLower(blocked)
+		return domain == blocked || strings.HasSuffix(domain, "."+blocked)
+	})
+	if blocked {
+		return invalidField("email", CodeBlocked, ErrEmailDomainBlocked, fmt.Sprintf("addresses at %s are not accepted", domain))
+	}
+	return nil
+}
+
+// emailProvider describes how a mail provider maps addresses to mailboxes.
+type emailProvider struct {
+	domain     string
+	ignoreDots bool
+	tagSep     string
+}
+
+var emailProviders = map[string]emailProvider{
+	"gmail.com":      {domain: "gmail.com", ignoreDots: true, tagSep: "+"},
+	"googlemail.com": {domain: "gmail.com", ignoreDots: true, tagSep: "+"},
+	"outlook.com":    {domain: "outlook.com", tagSep: "+"},
+	"hotmail.com":    {domain: "hotmail.com", tagSep: "+"},
+	"icloud.com":     {domain: "icloud.com", tagSep: "+"},
+	"fastmail.com":   {domain: "fastmail.com", tagSep: "+"},
+	"proton.me":      {domain: "proton.me", tagSep: "+"},
+	"protonmail.com": {domain: "proton.me", tagSep: "+"},
+}
+
+// CanonicalEmail returns the mailbox a normalized address delivers to at a
+// known provider, with dots and sub-address tags removed where the provider
+// ignores them. Other addresses are returned unchanged.
+func CanonicalEmail(email string) string {
+	email = strings.ToLower(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	provider, ok := emailProviders[domain]
+	if !ok {
+		return email
+	}
+	if provider.tagSep != "" {
+		local, _, _ = strings.Cut(local, provider.tagSep)
+	}
+	if provider.ignoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + provider.domain
+}
+
+// CanonicalEmailFinder is implemented by user repositories that can look a
+// user up by CanonicalEmail, typically through an index on it. Service only
+// needs it when EmailPolicy.CanonicalizeProviders is set, and falls back to
+// scanning UserRepository.List otherwise.
+type CanonicalEmailFinder interface {
+	GetByCanonicalEmail(ctx context.Context, canonical string) (*User, error)
+}
+
+// checkEmail validates email and makes sure it is available to userID. It
+// returns the normalized form to store.
+func (s *Service) checkEmail(ctx context.Context, email, userID string) (string, error) {
+	email, err := s.validateEmail(email)
+	if err != nil {
+		return "", err
+	}
+	return email, s.ensureEmailAvailable(ctx, email, userID)
+}
+
+// validateEmail normalizes email and checks it against the policy.
+func (s *Service) validateEmail(email string) (string, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+	return email, s.emailPolicy.Validate(email)
+}
+
+// ensureEmailAvailable makes sure no user other than userID has the
+// normalized email.
+func (s *Service) ensureEmailAvailable(ctx context.Context, email, userID string) error {
+	existing, err := s.findByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) || (err == nil && existing.ID == userID) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
+	}
+	return invalidField("email", CodeTaken, ErrEmailTaken, "is already taken")
+}
+
+func (s *Service) findByEmail(ctx context.Context, email string) (*User, error) {
+	if !s.emailPolicy.CanonicalizeProviders {
+		return s.userRepo.GetByEmail(ctx, email)
+	}
+
+	canonical := CanonicalEmail(email)
+	if finder, ok := s.userRepo.(CanonicalEmailFinder); ok {
+		return finder.GetByCanonicalEmail(ctx, canonical)
+	}
+	users, err := s.userRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if CanonicalEmail(user.Email) == canonical {
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
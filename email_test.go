@@ -0,0 +1,121 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	valid := map[string]string{
+		"bob@example.com":         "bob@example.com",
+		"  Bob@Example.COM ":      "bob@example.com",
+		"o'brien+tag@example.com": "o'brien+tag@example.com",
+		"user@münchen.de":         "user@xn--mnchen-3ya.de",
+		"user@example.com.":       "user@example.com",
+	}
+	for input, want := range valid {
+		got, err := NormalizeEmail(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{
+		"",
+		"bob",
+		"bob@",
+		"@example.com",
+		"bob@localhost",
+		"bob@exa_mple.com",
+		"a..b@example.com",
+		".bob@example.com",
+		"Bob <bob@example.com>",
+		"bob@example.com (work)",
+		`"bob smith"@example.com`,
+		strings.Repeat("a", MaxEmailLocalLength+1) + "@example.com",
+	} {
+		_, err := NormalizeEmail(input)
+		require.ErrorIs(t, err, ErrInvalidEmail, input)
+	}
+}
+
+func TestCanonicalEmail(t *testing.T) {
+	require.Equal(t, "jdoe@gmail.com", CanonicalEmail("j.doe+news@gmail.com"))
+	require.Equal(t, "jdoe@gmail.com", CanonicalEmail("j.doe@googlemail.com"))
+	require.Equal(t, "j.doe@outlook.com", CanonicalEmail("j.doe+x@outlook.com"))
+	require.Equal(t, "j.doe+x@example.com", CanonicalEmail("j.doe+x@example.com"))
+}
+
+func TestEmailPolicy(t *testing.T) {
+	policy := DefaultEmailPolicy()
+	require.NoError(t, policy.Validate("bob@example.com"))
+	require.ErrorIs(t, policy.Validate("bob@mailinator.com"), ErrEmailDomainBlocked)
+	require.ErrorIs(t, policy.Validate("bob@eu.mailinator.com"), ErrEmailDomainBlocked)
+	require.NoError(t, policy.Validate("bob@notmailinator.com"))
+
+	path := filepath.Join(t.TempDir(), "blocked.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# throwaway\nExample.ORG\n\nspam.test # temporary\n"), 0o600))
+	domains, err := LoadDomainListFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.org", "spam.test"}, domains)
+}
+
+func TestEmailUniqueness(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewMemoryUserRepository(), NewMemoryRoleRepository(), &mockHasher{}, &mockTokenizer{})
+
+	bob, err := svc.Register(ctx, UserRegisterInput{Email: "Bob@Example.com", Username: "bob", Password: "pw"})
+	require.NoError(t, err)
+	require.Equal(t, "bob@example.com", bob.Email)
+
+	_, err = svc.Register(ctx, UserRegisterInput{Email: "bob@example.com", Username: "bob2", Password: "pw"})
+	require.ErrorIs(t, err, ErrEmailTaken)
+	_, err = svc.Register(ctx, UserRegisterInput{Email: "not-an-email", Username: "bob3", Password: "pw"})
+	require.ErrorIs(t, err, ErrInvalidEmail)
+	_, err = svc.Register(ctx, UserRegisterInput{Email: "bob@yopmail.com", Username: "bob4", Password: "pw"})
+	require.ErrorIs(t, err, ErrEmailDomainBlocked)
+
+	_, err = svc.Login(ctx, UserLoginInput{Email: "BOB@example.com", Password: "pw"})
+	require.NoError(t, err)
+
+	t.Run("legacy unnormalized address", func(t *testing.T) {
+		userRepo := &mockUserRepo{users: map[string]*User{
+			"old": {ID: "old", Email: "Old@Example.com", HashedPassword: "hashed:pw"},
+		}}
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.Login(ctx, UserLoginInput{Email: "Old@Example.com", Password: "pw"})
+		require.NoError(t, err)
+	})
+
+	t.Run("lookup failure is not availability", func(t *testing.T) {
+		userRepo := &mockUserRepo{users: map[string]*User{}, getErr: errors.New("connection reset")}
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "new@example.com", Username: "new", Password: "pw"})
+		require.ErrorIs(t, err, ErrFailedToListUsers)
+	})
+
+	t.Run("provider canonicalization", func(t *testing.T) {
+		svc := NewService(NewMemoryUserRepository(), NewMemoryRoleRepository(), &mockHasher{}, &mockTokenizer{},
+			WithEmailPolicy(EmailPolicy{CanonicalizeProviders: true}))
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "jane.doe@gmail.com", Username: "jane", Password: "pw"})
+		require.NoError(t, err)
+		_, err = svc.Register(ctx, UserRegisterInput{Email: "janedoe+spam@googlemail.com", Username: "jane2", Password: "pw"})
+		require.ErrorIs(t, err, ErrEmailTaken)
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		other, err := svc.Register(ctx, UserRegisterInput{Email: "other@example.com", Username: "other", Password: "pw"})
+		require.NoError(t, err)
+		_, err = svc.UpdateUserProfile(ctx, other.ID, UserPatch{Email: ptr("BOB@example.com")})
+		require.ErrorIs(t, err, ErrEmailTaken)
+
+		updated, err := svc.UpdateUserProfile(ctx, other.ID, UserPatch{Email: ptr("Other@Example.com")})
+		require.NoError(t, err)
+		require.Equal(t, other.Version, updated.Version, "same address after normalization")
+	})
+}
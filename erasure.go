@@ -0,0 +1,128 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const PermissionUsersErase = "users:erase"
+
+// ErasedEmailDomain is the reserved domain used for pseudonymized addresses.
+const ErasedEmailDomain = "erased.invalid"
+
+// ErasureTombstone records that a user's personal data was erased. The user
+// record itself keeps its ID and carries ErasedAt as the durable proof.
+type ErasureTombstone struct {
+	UserID   string
+	ErasedAt time.Time
+	ErasedBy string
+}
+
+func WithSessionRevoker(revoker SessionRevoker) ServiceOption {
+	return func(s *Service) {
+		s.sessions = revoker
+	}
+}
+
+// EraseUser irreversibly replaces the user's personal data with random
+// pseudonyms, wipes credentials and revokes sessions. The user ID stays
+// stable so references held by other services remain valid. Pending
+// invitations to the user's address are revoked and pseudonymized too. Audit
+// entries and events about the user are kept, since the hash chain and
+// delivery guarantees forbid removing them; they refer to the user by ID and
+// never hold personal data values.
+func (s *Service) EraseUser(ctx context.Context, userID string) (*ErasureTombstone, error) {
+	if err := s.authorizeUser(ctx, PermissionUsersErase, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.ErasedAt != nil {
+		return nil, ErrUserErased
+	}
+
+	pseudonym, err := randomPseudonym()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToEraseUser, err)
+	}
+
+	if s.sessions != nil {
+		if err := s.sessions.RevokeUserSessions(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToEraseUser, err)
+		}
+	}
+
+	return inTx(ctx, s, func(ctx context.Context) (*ErasureTombstone, error) {
+		actor, _ := ActorFromContext(ctx)
+		erasedAt := s.now()
+		erased := *user
+		erased.Email = "erased-" + pseudonym + "@" + ErasedEmailDomain
+		erased.Username = "erased-" + pseudonym
+		erased.DisplayName = ""
+		erased.HashedPassword = ""
+		erased.Attributes = nil
+		erased.LastSeen = time.Time{}
+		erased.StatusReason = ""
+		erased.ErasedAt = &erasedAt
+
+		if _, err := s.userRepo.Update(ctx, erased); err != nil {
+			return nil, updateError(ErrFailedToEraseUser, err)
+		}
+		if err := s.eraseInvitations(ctx, user.Email, erased.Email); err != nil {
+			return nil, err
+		}
+
+		// The diff would copy the erased personal data into the audit log.
+		if err := s.audit(ctx, AuditUserErased, "user", user.ID, nil, nil); err != nil {
+			return nil, err
+		}
+		if err := s.publish(ctx, UserErased{EventMeta: EventMeta{UserID: user.ID, ActorID: actor.UserID, At: erasedAt}}); err != nil {
+			return nil, err
+		}
+		return &ErasureTombstone{UserID: user.ID, ErasedAt: erasedAt, ErasedBy: actor.UserID}, nil
+	})
+}
+
+// eraseInvitations revokes the pending invitations sent to email and replaces
+// the address on them with pseudonym.
+func (s *Service) eraseInvitations(ctx context.Context, email, pseudonym string) error {
+	if s.invitationRepo == nil || s.orgRepo == nil {
+		return nil
+	}
+
+	orgs, err := s.orgRepo.List(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToEraseUser, err)
+	}
+	for _, org := range orgs {
+		pending, err := s.invitationRepo.ListPending(ctx, org.ID)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrFailedToEraseUser, err)
+		}
+		for _, invitation := range pending {
+			if invitation.Email != email {
+				continue
+			}
+			invitation.Email = pseudonym
+			invitation.Status = InvitationRevoked
+			if _, err := s.invitationRepo.Update(ctx, invitation); err != nil {
+				return fmt.Errorf("%w: %v", ErrFailedToEraseUser, err)
+			}
+		}
+	}
+	return nil
+}
+
+func randomPseudonym() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
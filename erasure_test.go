@@ -0,0 +1,139 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockSessionRevoker struct {
+	revoked []string
+	err     error
+}
+
+func (m *mockSessionRevoker) RevokeUserSessions(ctx context.Context, userID string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.revoked = append(m.revoked, userID)
+	return nil
+}
+
+func TestEraseUser(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	newUserRepo := func() *mockUserRepo {
+		return &mockUserRepo{users: map[string]*User{
+			"alice": {
+				ID:             "alice",
+				Email:          "alice@example.com",
+				Username:       "alice",
+				HashedPassword: "hashed:pw",
+				RoleID:         "r-user",
+				Attributes:     map[string]string{"phone": "+15550100"},
+				LastSeen:       now,
+			},
+		}}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		userRepo := newUserRepo()
+		sessions := &mockSessionRevoker{}
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{},
+			WithClock(func() time.Time { return now }), WithSessionRevoker(sessions))
+
+		tombstone, err := svc.EraseUser(ContextWithActor(ctx, Actor{UserID: "dpo"}), "alice")
+		require.NoError(t, err)
+		require.Equal(t, &ErasureTombstone{UserID: "alice", ErasedAt: now, ErasedBy: "dpo"}, tombstone)
+		require.Equal(t, []string{"alice"}, sessions.revoked)
+
+		u := userRepo.users["alice"]
+		require.Equal(t, "alice", u.ID)
+		require.Equal(t, "r-user", u.RoleID)
+		require.True(t, strings.HasSuffix(u.Email, "@"+ErasedEmailDomain))
+		require.NotContains(t, u.Email, "alice")
+		require.NotContains(t, u.Username, "alice")
+		require.Empty(t, u.HashedPassword)
+		require.Nil(t, u.Attributes)
+		require.True(t, u.LastSeen.IsZero())
+		require.Equal(t, now, *u.ErasedAt)
+
+		_, err = svc.Login(ctx, UserLoginInput{Email: u.Email, Password: ""})
+		require.ErrorIs(t, err, ErrUserNotFound)
+
+		_, err = svc.EraseUser(ctx, "alice")
+		require.ErrorIs(t, err, ErrUserErased)
+	})
+
+	t.Run("audit log keeps no personal data", func(t *testing.T) {
+		auditLog := NewMemoryAuditLog()
+		svc := NewService(newUserRepo(), &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{}, WithAuditLog(auditLog))
+		_, err := svc.EraseUser(ctx, "alice")
+		require.NoError(t, err)
+
+		var export strings.Builder
+		require.NoError(t, svc.ExportAuditLog(ctx, AuditQuery{}, AuditExportJSONL, &export))
+		require.Contains(t, export.String(), `"target_id":"alice"`)
+		require.NotContains(t, export.String(), "alice@example.com")
+		require.NotContains(t, export.String(), "+15550100")
+	})
+
+	t.Run("pending invitations and events keep no personal data", func(t *testing.T) {
+		svc, _, _, _ := newInvitationService()
+		outbox := NewMemoryOutbox()
+		svc.outbox = outbox
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "carol@example.com", Username: "carol", Password: "pw"})
+		require.NoError(t, err)
+		carol, err := svc.userRepo.GetByEmail(ctx, "carol@example.com")
+		require.NoError(t, err)
+		invitation, err := svc.InviteToOrganization(ctx, "acme", "carol@example.com", "r-editor")
+		require.NoError(t, err)
+
+		_, err = svc.EraseUser(ctx, carol.ID)
+		require.NoError(t, err)
+
+		stored, err := svc.invitationRepo.GetByID(ctx, invitation.ID)
+		require.NoError(t, err)
+		require.Equal(t, InvitationRevoked, stored.Status)
+		require.True(t, strings.HasSuffix(stored.Email, "@"+ErasedEmailDomain))
+
+		messages, err := outbox.ListByStatus(ctx, OutboxPending, "", 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, messages)
+		for _, message := range messages {
+			require.NotContains(t, string(message.Payload), "carol")
+		}
+	})
+
+	t.Run("pseudonyms are unique", func(t *testing.T) {
+		userRepo := newUserRepo()
+		userRepo.users["bob"] = &User{ID: "bob", Email: "bob@example.com", Username: "bob"}
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+
+		_, err := svc.EraseUser(ctx, "alice")
+		require.NoError(t, err)
+		_, err = svc.EraseUser(ctx, "bob")
+		require.NoError(t, err)
+		require.NotEqual(t, userRepo.users["alice"].Email, userRepo.users["bob"].Email)
+	})
+
+	t.Run("session revocation fails", func(t *testing.T) {
+		userRepo := newUserRepo()
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{},
+			WithSessionRevoker(&mockSessionRevoker{err: errors.New("fail")}))
+
+		_, err := svc.EraseUser(ctx, "alice")
+		require.ErrorIs(t, err, ErrFailedToEraseUser)
+		require.Equal(t, "alice@example.com", userRepo.users["alice"].Email)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc := NewService(newUserRepo(), &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.EraseUser(ctx, "ghost")
+		require.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
@@ -1,17 +1,163 @@
 package users
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorCategory groups errors by how a caller should react to them. Adapters
+// map a category to a transport status with HTTPStatus rather than matching
+// individual errors.
+type ErrorCategory string
+
+const (
+	CategoryInvalid         ErrorCategory = "invalid"
+	CategoryUnauthenticated ErrorCategory = "unauthenticated"
+	CategoryForbidden       ErrorCategory = "forbidden"
+	CategoryNotFound        ErrorCategory = "not_found"
+	CategoryConflict        ErrorCategory = "conflict"
+	CategoryGone            ErrorCategory = "gone"
+	CategoryNotConfigured   ErrorCategory = "not_configured"
+	CategoryUnavailable     ErrorCategory = "unavailable"
+	CategoryInternal        ErrorCategory = "internal"
+)
+
+// HTTPStatus returns the HTTP status code suggested for errors in the
+// category.
+func (c ErrorCategory) HTTPStatus() int {
+	switch c {
+	case CategoryInvalid:
+		return http.StatusBadRequest
+	case CategoryUnauthenticated:
+		return http.StatusUnauthorized
+	case CategoryForbidden:
+		return http.StatusForbidden
+	case CategoryNotFound:
+		return http.StatusNotFound
+	case CategoryConflict:
+		return http.StatusConflict
+	case CategoryGone:
+		return http.StatusGone
+	case CategoryNotConfigured:
+		return http.StatusNotImplemented
+	case CategoryUnavailable:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is the type of every sentinel below. Code is stable across releases
+// and safe for clients to switch on; Message is for humans and may change.
+type Error struct {
+	Code     string
+	Category ErrorCategory
+	Message  string
+}
+
+func newError(code string, category ErrorCategory, message string) *Error {
+	return &Error{Code: code, Category: category, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrorCode returns the code of the Error wrapped by err: "validation_failed"
+// for a ValidationError, and "internal" when err wraps no Error at all.
+func ErrorCode(err error) string {
+	return asError(err).Code
+}
+
+// ErrorCategoryOf returns the category of the Error wrapped by err, with the
+// same fallbacks as ErrorCode.
+func ErrorCategoryOf(err error) ErrorCategory {
+	return asError(err).Category
+}
+
+var (
+	errValidationFailed = newError("validation_failed", CategoryInvalid, "validation failed")
+	errInternal         = newError("internal", CategoryInternal, "internal error")
+)
+
+// asError finds the Error that classifies err. A ValidationError is checked
+// first because it unwraps to the sentinel of every invalid field.
+func asError(err error) *Error {
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return errValidationFailed
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return errInternal
+}
 
 var (
-	ErrInvalidCredentials    = errors.New("invalid credentials")
-	ErrUserNotFound          = errors.New("user not found")
-	ErrEmailTaken            = errors.New("email already taken")
-	ErrUsernameAlreadyExists = errors.New("username already exists")
-	ErrFailedToCreateRole    = errors.New("failed to create role")
-	ErrFailedToUpdateUser    = errors.New("failed to update user")
-	ErrFailedToDeleteUser    = errors.New("failed to delete user")
-	ErrFailedToListUsers     = errors.New("failed to list users")
-	ErrRoleNotFound          = errors.New("role not found")
-	ErrFailedToHashPassword  = errors.New("failed to hash password")
-	ErrCannotUseSamePassword = errors.New("cannot use the same password")
+	ErrInvalidCredentials         = newError("invalid_credentials", CategoryUnauthenticated, "invalid credentials")
+	ErrAccountSuspended           = newError("account_suspended", CategoryForbidden, "account suspended")
+	ErrUserNotFound               = newError("user_not_found", CategoryNotFound, "user not found")
+	ErrEmailTaken                 = newError("email_taken", CategoryConflict, "email already taken")
+	ErrInvalidEmail               = newError("invalid_email", CategoryInvalid, "invalid email")
+	ErrEmailDomainBlocked         = newError("email_domain_blocked", CategoryInvalid, "email domain is not allowed")
+	ErrUsernameAlreadyExists      = newError("username_already_exists", CategoryConflict, "username already exists")
+	ErrInvalidUsername            = newError("invalid_username", CategoryInvalid, "invalid username")
+	ErrUsernameReserved           = newError("username_reserved", CategoryInvalid, "username is reserved")
+	ErrFailedToCreateRole         = newError("failed_to_create_role", CategoryInternal, "failed to create role")
+	ErrFailedToUpdateRole         = newError("failed_to_update_role", CategoryInternal, "failed to update role")
+	ErrRoleCycle                  = newError("role_cycle", CategoryConflict, "role hierarchy cycle")
+	ErrFailedToUpdateUser         = newError("failed_to_update_user", CategoryInternal, "failed to update user")
+	ErrConflict                   = newError("conflict", CategoryConflict, "record was modified concurrently")
+	ErrInvalidProfile             = newError("invalid_profile", CategoryInvalid, "invalid profile")
+	ErrFailedToDeleteUser         = newError("failed_to_delete_user", CategoryInternal, "failed to delete user")
+	ErrUserNotDeleted             = newError("user_not_deleted", CategoryConflict, "user is not deleted")
+	ErrFailedToExportUserData     = newError("failed_to_export_user_data", CategoryInternal, "failed to export user data")
+	ErrUserErased                 = newError("user_erased", CategoryGone, "user has been erased")
+	ErrFailedToEraseUser          = newError("failed_to_erase_user", CategoryInternal, "failed to erase user")
+	ErrFailedToListUsers          = newError("failed_to_list_users", CategoryInternal, "failed to list users")
+	ErrRoleNotFound               = newError("role_not_found", CategoryNotFound, "role not found")
+	ErrFailedToHashPassword       = newError("failed_to_hash_password", CategoryInternal, "failed to hash password")
+	ErrCannotUseSamePassword      = newError("cannot_use_same_password", CategoryInvalid, "cannot use the same password")
+	ErrInvalidPassword            = newError("invalid_password", CategoryInvalid, "invalid password")
+	ErrInvalidPolicy              = newError("invalid_policy", CategoryInvalid, "invalid policy")
+	ErrPolicyNotConfigured        = newError("policy_not_configured", CategoryNotConfigured, "policy engine not configured")
+	ErrForbidden                  = newError("forbidden", CategoryForbidden, "forbidden")
+	ErrOrganizationNotFound       = newError("organization_not_found", CategoryNotFound, "organization not found")
+	ErrMembershipNotFound         = newError("membership_not_found", CategoryNotFound, "membership not found")
+	ErrAlreadyMember              = newError("already_member", CategoryConflict, "user is already a member of the organization")
+	ErrFailedToCreateOrganization = newError("failed_to_create_organization", CategoryInternal, "failed to create organization")
+	ErrFailedToUpdateMembership   = newError("failed_to_update_membership", CategoryInternal, "failed to update membership")
+	ErrOrganizationsNotConfigured = newError("organizations_not_configured", CategoryNotConfigured, "organization repository not configured")
+	ErrInvalidInvitation          = newError("invalid_invitation", CategoryInvalid, "invalid invitation token")
+	ErrInvitationExpired          = newError("invitation_expired", CategoryGone, "invitation expired")
+	ErrInvitationNotPending       = newError("invitation_not_pending", CategoryConflict, "invitation is no longer pending")
+	ErrFailedToCreateInvitation   = newError("failed_to_create_invitation", CategoryInternal, "failed to create invitation")
+	ErrFailedToUpdateInvitation   = newError("failed_to_update_invitation", CategoryInternal, "failed to update invitation")
+	ErrFailedToSendInvitation     = newError("failed_to_send_invitation", CategoryUnavailable, "failed to send invitation")
+	ErrInvitationsNotConfigured   = newError("invitations_not_configured", CategoryNotConfigured, "invitations not configured")
+	ErrGroupNotFound              = newError("group_not_found", CategoryNotFound, "group not found")
+	ErrGroupCycle                 = newError("group_cycle", CategoryConflict, "group nesting cycle")
+	ErrFailedToCreateGroup        = newError("failed_to_create_group", CategoryInternal, "failed to create group")
+	ErrFailedToUpdateGroup        = newError("failed_to_update_group", CategoryInternal, "failed to update group")
+	ErrGroupsNotConfigured        = newError("groups_not_configured", CategoryNotConfigured, "group repository not configured")
+	ErrInvalidRelationTuple       = newError("invalid_relation_tuple", CategoryInvalid, "invalid relation tuple")
+	ErrRelationDepthExceeded      = newError("relation_depth_exceeded", CategoryInternal, "relation check depth exceeded")
+	ErrRelationsNotConfigured     = newError("relations_not_configured", CategoryNotConfigured, "relation checker not configured")
+	ErrFailedToWriteOutbox        = newError("failed_to_write_outbox", CategoryInternal, "failed to write outbox message")
+	ErrFailedToWriteAudit         = newError("failed_to_write_audit", CategoryInternal, "failed to write audit entry")
+	ErrAuditChainBroken           = newError("audit_chain_broken", CategoryInternal, "audit chain broken")
+	ErrAuditLogNotConfigured      = newError("audit_log_not_configured", CategoryNotConfigured, "audit log not configured")
+	ErrInvalidCursor              = newError("invalid_cursor", CategoryInvalid, "invalid cursor")
+	ErrInvalidUserQuery           = newError("invalid_user_query", CategoryInvalid, "invalid user query")
+	ErrSearchNotConfigured        = newError("search_not_configured", CategoryNotConfigured, "user search index not configured")
+	ErrUnsupportedExportFormat    = newError("unsupported_export_format", CategoryInvalid, "unsupported export format")
+	ErrWebhookNotFound            = newError("webhook_not_found", CategoryNotFound, "webhook subscription not found")
+	ErrInvalidWebhookURL          = newError("invalid_webhook_url", CategoryInvalid, "invalid webhook url")
+	ErrInvalidWebhookSecret       = newError("invalid_webhook_secret", CategoryInvalid, "invalid webhook secret")
+	ErrFailedToCreateWebhook      = newError("failed_to_create_webhook", CategoryInternal, "failed to create webhook subscription")
+	ErrWebhookDeliveryFailed      = newError("webhook_delivery_failed", CategoryUnavailable, "webhook delivery failed")
+	ErrInvalidWebhookSignature    = newError("invalid_webhook_signature", CategoryUnauthenticated, "invalid webhook signature")
+	ErrWebhookSignatureExpired    = newError("webhook_signature_expired", CategoryUnauthenticated, "webhook signature expired")
+	ErrWebhooksNotConfigured      = newError("webhooks_not_configured", CategoryNotConfigured, "webhook repository not configured")
 )
@@ -0,0 +1,188 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	EventUserRegistered    = "user.registered"
+	EventUserLoggedIn      = "user.logged_in"
+	EventUserUpdated       = "user.updated"
+	EventProfileUpdated    = "user.profile_updated"
+	EventPasswordChanged   = "user.password_changed"
+	EventRoleAssigned      = "user.role_assigned"
+	EventUserStatusChanged = "user.status_changed"
+	EventUserDeleted       = "user.deleted"
+	EventUserRestored      = "user.restored"
+	EventUserPurged        = "user.purged"
+	EventUserErased        = "user.erased"
+
+	// AllEvents subscribes an EventBus handler to every event type.
+	AllEvents = "*"
+)
+
+// Event is a fact about a user that Service publishes after a successful
+// mutation.
+type Event interface {
+	EventType() string
+	AggregateID() string
+	OccurredAt() time.Time
+}
+
+// EventMeta holds the fields shared by every event. ActorID is empty when
+// the change was not made on behalf of a user, such as a self-registration.
+type EventMeta struct {
+	UserID  string    `json:"user_id"`
+	ActorID string    `json:"actor_id,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+func (m EventMeta) AggregateID() string   { return m.UserID }
+func (m EventMeta) OccurredAt() time.Time { return m.At }
+
+// UserRegistered carries no personal data, since outboxes and webhook retries
+// keep payloads beyond an erasure; subscribers look the user up by ID.
+type UserRegistered struct {
+	EventMeta
+	RoleID string `json:"role_id"`
+}
+
+type UserLoggedIn struct {
+	EventMeta
+}
+
+type UserUpdated struct {
+	EventMeta
+}
+
+// ProfileUpdated lists the UserPatch fields that actually changed, by their
+// JSON names. It carries no values so personal data stays out of events.
+type ProfileUpdated struct {
+	EventMeta
+	ChangedFields []string `json:"changed_fields"`
+}
+
+// PasswordChanged is published for both self-service changes and
+// administrative resets, distinguished by Reset.
+type PasswordChanged struct {
+	EventMeta
+	Reset bool `json:"reset"`
+}
+
+type RoleAssigned struct {
+	EventMeta
+	RoleID         string `json:"role_id"`
+	PreviousRoleID string `json:"previous_role_id,omitempty"`
+}
+
+type UserStatusChanged struct {
+	EventMeta
+	Status    UserStatus `json:"status"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type UserDeleted struct {
+	EventMeta
+}
+
+type UserRestored struct {
+	EventMeta
+}
+
+type UserPurged struct {
+	EventMeta
+}
+
+type UserErased struct {
+	EventMeta
+}
+
+func (UserRegistered) EventType() string    { return EventUserRegistered }
+func (UserLoggedIn) EventType() string      { return EventUserLoggedIn }
+func (UserUpdated) EventType() string       { return EventUserUpdated }
+func (ProfileUpdated) EventType() string    { return EventProfileUpdated }
+func (PasswordChanged) EventType() string   { return EventPasswordChanged }
+func (RoleAssigned) EventType() string      { return EventRoleAssigned }
+func (UserStatusChanged) EventType() string { return EventUserStatusChanged }
+func (UserDeleted) EventType() string       { return EventUserDeleted }
+func (UserRestored) EventType() string      { return EventUserRestored }
+func (UserPurged) EventType() string        { return EventUserPurged }
+func (UserErased) EventType() string        { return EventUserErased }
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+type EventHandler func(ctx context.Context, event Event) error
+
+// EventBus is a synchronous in-process EventPublisher. Handlers run in
+// subscription order on the publishing goroutine.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: map[string][]EventHandler{}}
+}
+
+// Subscribe registers handler for eventType, or for every event when
+// eventType is AllEvents.
+func (b *EventBus) Subscribe(eventType string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish runs every matching handler, even when an earlier one fails, and
+// returns the joined handler errors.
+func (b *EventBus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := append([]EventHandler{}, b.handlers[event.EventType()]...)
+	handlers = append(handlers, b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func WithEventPublisher(publisher EventPublisher) ServiceOption {
+	return func(s *Service) {
+		s.events = publisher
+	}
+}
+
+// eventMeta stamps an event for userID with the actor and the current time.
+func (s *Service) eventMeta(ctx context.Context, userID string) EventMeta {
+	actor, _ := ActorFromContext(ctx)
+	return EventMeta{UserID: userID, ActorID: actor.UserID, At: s.now()}
+}
+
+// publish records event in the outbox when one is configured, otherwise it
+// delivers it straight to the publisher. An outbox write failure is returned
+// so that the mutation's transaction (see WithTxManager) rolls back with it.
+// Direct delivery and the search index refresh every event triggers wait
+// until that transaction commits; a failing subscriber is ignored, since by
+// then the mutation is stored.
+func (s *Service) publish(ctx context.Context, event Event) error {
+	afterCommit(ctx, func(ctx context.Context) {
+		s.syncSearchIndex(ctx, event)
+		if s.outbox == nil && s.events != nil {
+			_ = s.events.Publish(ctx, event)
+		}
+	})
+
+	if s.outbox != nil {
+		return s.appendToOutbox(ctx, event)
+	}
+	return nil
+}
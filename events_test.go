@@ -0,0 +1,100 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher struct {
+	events []Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) types() []string {
+	var types []string
+	for _, e := range p.events {
+		types = append(types, e.EventType())
+	}
+	return types
+}
+
+func TestEventBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewEventBus()
+	var got []string
+	bus.Subscribe(EventUserDeleted, func(ctx context.Context, event Event) error {
+		got = append(got, "deleted:"+event.AggregateID())
+		return errors.New("handler failed")
+	})
+	bus.Subscribe(AllEvents, func(ctx context.Context, event Event) error {
+		got = append(got, "all:"+event.EventType())
+		return nil
+	})
+
+	err := bus.Publish(ctx, UserDeleted{EventMeta{UserID: "u1"}})
+	require.Error(t, err)
+	require.NoError(t, bus.Publish(ctx, UserLoggedIn{EventMeta{UserID: "u1"}}))
+	require.Equal(t, []string{"deleted:u1", "all:user.deleted", "all:user.logged_in"}, got)
+}
+
+func TestServicePublishesEvents(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	userRepo := &mockUserRepo{users: map[string]*User{}}
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{
+		"r-user":  {ID: "r-user", Name: RoleUser},
+		"r-admin": {ID: "r-admin", Name: RoleAdmin},
+	}}
+	events := &recordingPublisher{}
+	svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{},
+		WithClock(func() time.Time { return now }), WithEventPublisher(events))
+	ctx := ContextWithActor(context.Background(), Actor{UserID: "admin"})
+
+	user, err := svc.Register(context.Background(), UserRegisterInput{Email: "e@example.com", Username: "e", Password: "pw"})
+	require.NoError(t, err)
+	_, err = svc.Login(context.Background(), UserLoginInput{Email: "e@example.com", Password: "pw"})
+	require.NoError(t, err)
+	_, err = svc.ChangePassword(ctx, user.ID, "pw", "pw2")
+	require.NoError(t, err)
+	_, err = svc.ResetPassword(ctx, user.ID, "pw3")
+	require.NoError(t, err)
+	_, err = svc.AssignRoleToUser(ctx, user.ID, "r-admin")
+	require.NoError(t, err)
+	_, err = svc.SuspendUser(ctx, user.ID, "spam", nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteUser(ctx, user.ID))
+
+	require.Equal(t, []string{
+		EventUserRegistered,
+		EventUserLoggedIn,
+		EventPasswordChanged,
+		EventPasswordChanged,
+		EventRoleAssigned,
+		EventUserStatusChanged,
+		EventUserDeleted,
+	}, events.types())
+
+	registered := events.events[0].(UserRegistered)
+	require.Equal(t, user.RoleID, registered.RoleID)
+	require.Empty(t, registered.ActorID)
+	require.True(t, events.events[3].(PasswordChanged).Reset)
+	require.Equal(t, RoleAssigned{
+		EventMeta:      EventMeta{UserID: user.ID, ActorID: "admin", At: now},
+		RoleID:         "r-admin",
+		PreviousRoleID: "r-user",
+	}, events.events[4])
+
+	t.Run("failed mutations publish nothing", func(t *testing.T) {
+		events.events = nil
+		_, err := svc.ResetPassword(ctx, "ghost", "pw")
+		require.ErrorIs(t, err, ErrUserNotFound)
+		require.Empty(t, events.events)
+	})
+}
@@ -0,0 +1,336 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Group collects users and other groups so roles can be granted to all of
+// them at once.
+type Group struct {
+	ID          string
+	Name        string
+	Description string
+	RoleIDs     []string
+}
+
+type GroupMemberKind string
+
+const (
+	GroupMemberUser  GroupMemberKind = "user"
+	GroupMemberGroup GroupMemberKind = "group"
+)
+
+type GroupMember struct {
+	GroupID  string
+	Kind     GroupMemberKind
+	MemberID string
+}
+
+const PermissionGroupsManage = "groups:manage"
+
+func WithGroupRepository(repo GroupRepository) ServiceOption {
+	return func(s *Service) {
+		s.groupRepo = repo
+	}
+}
+
+func (s *Service) CreateGroup(ctx context.Context, group Group) (*Group, error) {
+	if s.groupRepo == nil {
+		return nil, ErrGroupsNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionGroupsManage, Resource{Type: "group", ID: group.ID}); err != nil {
+		return nil, err
+	}
+	if len(group.RoleIDs) > 0 {
+		if err := s.authorize(ctx, PermissionRolesAssign, Resource{Type: "group", ID: group.ID}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, roleID := range group.RoleIDs {
+		if _, err := s.roleRepo.GetByID(ctx, roleID); err != nil {
+			return nil, ErrRoleNotFound
+		}
+	}
+
+	createdGroup, err := s.groupRepo.Create(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToCreateGroup, err)
+	}
+
+	if err := s.audit(ctx, AuditGroupCreated, "group", createdGroup.ID, nil, createdGroup); err != nil {
+		return nil, err
+	}
+	return createdGroup, nil
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, groupID string) error {
+	if s.groupRepo == nil {
+		return ErrGroupsNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionGroupsManage, Resource{Type: "group", ID: groupID}); err != nil {
+		return err
+	}
+
+	if err := s.groupRepo.Delete(ctx, groupID); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToUpdateGroup, err)
+	}
+	return s.audit(ctx, AuditGroupDeleted, "group", groupID, nil, nil)
+}
+
+func (s *Service) ListGroupMembers(ctx context.Context, groupID string) ([]GroupMember, error) {
+	if s.groupRepo == nil {
+		return nil, ErrGroupsNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionGroupsManage, Resource{Type: "group", ID: groupID}); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return nil, ErrGroupNotFound
+	}
+	return s.groupRepo.ListMembers(ctx, groupID)
+}
+
+func (s *Service) AddUserToGroup(ctx context.Context, groupID, userID string) error {
+	if _, err := s.activeUser(ctx, userID); err != nil {
+		return ErrUserNotFound
+	}
+	return s.addGroupMember(ctx, GroupMember{GroupID: groupID, Kind: GroupMemberUser, MemberID: userID})
+}
+
+func (s *Service) RemoveUserFromGroup(ctx context.Context, groupID, userID string) error {
+	return s.removeGroupMember(ctx, GroupMember{GroupID: groupID, Kind: GroupMemberUser, MemberID: userID})
+}
+
+// AddGroupToGroup nests child inside parent, so members of child inherit the
+// roles granted to parent. Nesting that would make a group contain itself is
+// rejected with ErrGroupCycle.
+func (s *Service) AddGroupToGroup(ctx context.Context, parentID, childID string) error {
+	if s.groupRepo == nil {
+		return ErrGroupsNotConfigured
+	}
+	if _, err := s.groupRepo.GetByID(ctx, childID); err != nil {
+		return ErrGroupNotFound
+	}
+
+	ancestors, err := s.groupAncestors(ctx, GroupMemberGroup, parentID)
+	if err != nil {
+		return err
+	}
+	if _, nested := ancestors[childID]; nested || parentID == childID {
+		return fmt.Errorf("%w: %s already contains %s", ErrGroupCycle, childID, parentID)
+	}
+
+	return s.addGroupMember(ctx, GroupMember{GroupID: parentID, Kind: GroupMemberGroup, MemberID: childID})
+}
+
+func (s *Service) RemoveGroupFromGroup(ctx context.Context, parentID, childID string) error {
+	return s.removeGroupMember(ctx, GroupMember{GroupID: parentID, Kind: GroupMemberGroup, MemberID: childID})
+}
+
+func (s *Service) addGroupMember(ctx context.Context, member GroupMember) error {
+	if s.groupRepo == nil {
+		return ErrGroupsNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionGroupsManage, Resource{Type: "group", ID: member.GroupID}); err != nil {
+		return err
+	}
+
+	group, err := s.groupRepo.GetByID(ctx, member.GroupID)
+	if err != nil {
+		return ErrGroupNotFound
+	}
+	// Joining a group confers its roles and those of every group above it,
+	// so it needs the permission that granting them directly would.
+	grantsRoles, err := s.groupGrantsRoles(ctx, group)
+	if err != nil {
+		return err
+	}
+	if grantsRoles {
+		if err := s.authorize(ctx, PermissionRolesAssign, Resource{Type: "group", ID: group.ID}); err != nil {
+			return err
+		}
+	}
+
+	if err := s.groupRepo.AddMember(ctx, member); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToUpdateGroup, err)
+	}
+	return s.audit(ctx, AuditGroupMemberAdded, "group", member.GroupID, nil, member)
+}
+
+// groupGrantsRoles reports whether members of group receive any role, either
+// from group itself or from a group it is nested in.
+func (s *Service) groupGrantsRoles(ctx context.Context, group *Group) (bool, error) {
+	if len(group.RoleIDs) > 0 {
+		return true, nil
+	}
+	ancestors, err := s.groupAncestors(ctx, GroupMemberGroup, group.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, ancestor := range ancestors {
+		if len(ancestor.RoleIDs) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) removeGroupMember(ctx context.Context, member GroupMember) error {
+	if s.groupRepo == nil {
+		return ErrGroupsNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionGroupsManage, Resource{Type: "group", ID: member.GroupID}); err != nil {
+		return err
+	}
+
+	if err := s.groupRepo.RemoveMember(ctx, member); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToUpdateGroup, err)
+	}
+	return s.audit(ctx, AuditGroupMemberRemoved, "group", member.GroupID, member, nil)
+}
+
+func (s *Service) GrantRoleToGroup(ctx context.Context, groupID, roleID string) (*Group, error) {
+	return s.updateGroupRoles(ctx, groupID, roleID, func(roleIDs []string) []string {
+		for _, id := range roleIDs {
+			if id == roleID {
+				return roleIDs
+			}
+		}
+		return append(roleIDs, roleID)
+	})
+}
+
+func (s *Service) RevokeRoleFromGroup(ctx context.Context, groupID, roleID string) (*Group, error) {
+	return s.updateGroupRoles(ctx, groupID, roleID, func(roleIDs []string) []string {
+		kept := make([]string, 0, len(roleIDs))
+		for _, id := range roleIDs {
+			if id != roleID {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	})
+}
+
+func (s *Service) updateGroupRoles(ctx context.Context, groupID, roleID string, change func([]string) []string) (*Group, error) {
+	if s.groupRepo == nil {
+		return nil, ErrGroupsNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionRolesAssign, Resource{Type: "group", ID: groupID}); err != nil {
+		return nil, err
+	}
+
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+	if _, err := s.roleRepo.GetByID(ctx, roleID); err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	before := *group
+	group.RoleIDs = change(append([]string(nil), group.RoleIDs...))
+	updatedGroup, err := s.groupRepo.Update(ctx, *group)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToUpdateGroup, err)
+	}
+
+	if err := s.audit(ctx, AuditGroupRolesChanged, "group", groupID, before, updatedGroup); err != nil {
+		return nil, err
+	}
+	return updatedGroup, nil
+}
+
+// EffectiveRoles returns the user's direct role together with every role
+// granted to a group the user belongs to, directly or through nested groups.
+func (s *Service) EffectiveRoles(ctx context.Context, userID string) ([]Role, error) {
+	if err := s.authorizeUser(ctx, PermissionUsersRead, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.activeUser(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	roleIDs, err := s.effectiveRoleIDs(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]Role, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		role, err := s.roleRepo.GetByID(ctx, roleID)
+		if err != nil {
+			continue
+		}
+		roles = append(roles, *role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (s *Service) effectiveRoleIDs(ctx context.Context, user *User) ([]string, error) {
+	seen := map[string]bool{}
+	var roleIDs []string
+	add := func(roleID string) {
+		if roleID != "" && !seen[roleID] {
+			seen[roleID] = true
+			roleIDs = append(roleIDs, roleID)
+		}
+	}
+
+	add(user.RoleID)
+	if s.groupRepo == nil {
+		return roleIDs, nil
+	}
+
+	groups, err := s.groupAncestors(ctx, GroupMemberUser, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, roleID := range groups[id].RoleIDs {
+			add(roleID)
+		}
+	}
+	return roleIDs, nil
+}
+
+// groupAncestors walks up from a member to every group that contains it,
+// directly or transitively. The visited set keeps cyclic data from looping.
+func (s *Service) groupAncestors(ctx context.Context, kind GroupMemberKind, memberID string) (map[string]*Group, error) {
+	ancestors := map[string]*Group{}
+	type pendingMember struct {
+		kind GroupMemberKind
+		id   string
+	}
+	pending := []pendingMember{{kind, memberID}}
+
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		groups, err := s.groupRepo.ListGroupsContaining(ctx, current.kind, current.id)
+		if err != nil {
+			return nil, err
+		}
+		for i := range groups {
+			group := groups[i]
+			if _, ok := ancestors[group.ID]; ok {
+				continue
+			}
+			ancestors[group.ID] = &group
+			pending = append(pending, pendingMember{GroupMemberGroup, group.ID})
+		}
+	}
+	return ancestors, nil
+}
@@ -0,0 +1,17 @@
+package users
+
+import "context"
+
+type GroupRepository interface {
+	Create(ctx context.Context, group Group) (*Group, error)
+	Update(ctx context.Context, group Group) (*Group, error)
+	Delete(ctx context.Context, id string) error
+	GetByID(ctx context.Context, id string) (*Group, error)
+	List(ctx context.Context) ([]Group, error)
+
+	AddMember(ctx context.Context, member GroupMember) error
+	RemoveMember(ctx context.Context, member GroupMember) error
+	ListMembers(ctx context.Context, groupID string) ([]GroupMember, error)
+	// ListGroupsContaining returns the groups the member belongs to directly.
+	ListGroupsContaining(ctx context.Context, kind GroupMemberKind, memberID string) ([]Group, error)
+}
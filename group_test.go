@@ -0,0 +1,214 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockGroupRepo struct {
+	groups  map[string]*Group
+	members map[GroupMember]bool
+}
+
+func newMockGroupRepo(groups ...Group) *mockGroupRepo {
+	m := &mockGroupRepo{groups: map[string]*Group{}, members: map[GroupMember]bool{}}
+	for _, g := range groups {
+		g := g
+		m.groups[g.ID] = &g
+	}
+	return m
+}
+
+func (m *mockGroupRepo) Create(ctx context.Context, g Group) (*Group, error) {
+	m.groups[g.ID] = &g
+	return &g, nil
+}
+func (m *mockGroupRepo) Update(ctx context.Context, g Group) (*Group, error) {
+	m.groups[g.ID] = &g
+	return &g, nil
+}
+func (m *mockGroupRepo) Delete(ctx context.Context, id string) error {
+	delete(m.groups, id)
+	return nil
+}
+func (m *mockGroupRepo) GetByID(ctx context.Context, id string) (*Group, error) {
+	g, ok := m.groups[id]
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+	copied := *g
+	return &copied, nil
+}
+func (m *mockGroupRepo) List(ctx context.Context) ([]Group, error) {
+	var gs []Group
+	for _, g := range m.groups {
+		gs = append(gs, *g)
+	}
+	return gs, nil
+}
+func (m *mockGroupRepo) AddMember(ctx context.Context, member GroupMember) error {
+	m.members[member] = true
+	return nil
+}
+func (m *mockGroupRepo) RemoveMember(ctx context.Context, member GroupMember) error {
+	delete(m.members, member)
+	return nil
+}
+func (m *mockGroupRepo) ListMembers(ctx context.Context, groupID string) ([]GroupMember, error) {
+	var ms []GroupMember
+	for member := range m.members {
+		if member.GroupID == groupID {
+			ms = append(ms, member)
+		}
+	}
+	return ms, nil
+}
+func (m *mockGroupRepo) ListGroupsContaining(ctx context.Context, kind GroupMemberKind, memberID string) ([]Group, error) {
+	var gs []Group
+	for member := range m.members {
+		if member.Kind == kind && member.MemberID == memberID {
+			gs = append(gs, *m.groups[member.GroupID])
+		}
+	}
+	return gs, nil
+}
+
+func newGroupService() (*Service, *mockGroupRepo) {
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"alice": {ID: "alice", RoleID: "r-user"},
+		"bob":   {ID: "bob", RoleID: "r-user"},
+	}}
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{
+		"r-user":    {ID: "r-user", Name: RoleUser},
+		"r-support": {ID: "r-support", Name: "support", Permissions: []string{PermissionUsersList}},
+		"r-billing": {ID: "r-billing", Name: "billing"},
+	}}
+	groupRepo := newMockGroupRepo(
+		Group{ID: "staff", Name: "Staff"},
+		Group{ID: "support", Name: "Support", RoleIDs: []string{"r-support"}},
+		Group{ID: "emea", Name: "EMEA support"},
+	)
+	return NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithGroupRepository(groupRepo)), groupRepo
+}
+
+func roleNames(roles []Role) []string {
+	var names []string
+	for _, r := range roles {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestEffectiveRoles(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newGroupService()
+
+	require.NoError(t, svc.AddGroupToGroup(ctx, "staff", "support"))
+	require.NoError(t, svc.AddGroupToGroup(ctx, "support", "emea"))
+	require.NoError(t, svc.AddUserToGroup(ctx, "emea", "alice"))
+	_, err := svc.GrantRoleToGroup(ctx, "staff", "r-billing")
+	require.NoError(t, err)
+
+	t.Run("unions direct and nested group roles", func(t *testing.T) {
+		roles, err := svc.EffectiveRoles(ctx, "alice")
+		require.NoError(t, err)
+		require.Equal(t, []string{"billing", "support", RoleUser}, roleNames(roles))
+	})
+
+	t.Run("user without groups", func(t *testing.T) {
+		roles, err := svc.EffectiveRoles(ctx, "bob")
+		require.NoError(t, err)
+		require.Equal(t, []string{RoleUser}, roleNames(roles))
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		_, err := svc.RevokeRoleFromGroup(ctx, "staff", "r-billing")
+		require.NoError(t, err)
+		roles, err := svc.EffectiveRoles(ctx, "alice")
+		require.NoError(t, err)
+		require.Equal(t, []string{"support", RoleUser}, roleNames(roles))
+	})
+
+	t.Run("group roles grant permissions", func(t *testing.T) {
+		svc.enforceAuthz = true
+		defer func() { svc.enforceAuthz = false }()
+		_, err := svc.ListUsers(actorCtx("alice"))
+		require.NoError(t, err)
+		_, err = svc.ListUsers(actorCtx("bob"))
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+}
+
+func TestGroupNestingCycles(t *testing.T) {
+	ctx := context.Background()
+	svc, groupRepo := newGroupService()
+
+	require.NoError(t, svc.AddGroupToGroup(ctx, "staff", "support"))
+	require.NoError(t, svc.AddGroupToGroup(ctx, "support", "emea"))
+
+	require.ErrorIs(t, svc.AddGroupToGroup(ctx, "emea", "staff"), ErrGroupCycle)
+	require.ErrorIs(t, svc.AddGroupToGroup(ctx, "staff", "staff"), ErrGroupCycle)
+	require.ErrorIs(t, svc.AddGroupToGroup(ctx, "staff", "missing"), ErrGroupNotFound)
+
+	t.Run("resolution survives cyclic data", func(t *testing.T) {
+		groupRepo.members[GroupMember{GroupID: "emea", Kind: GroupMemberGroup, MemberID: "staff"}] = true
+		require.NoError(t, svc.AddUserToGroup(ctx, "staff", "bob"))
+		roles, err := svc.EffectiveRoles(ctx, "bob")
+		require.NoError(t, err)
+		require.Equal(t, []string{"support", RoleUser}, roleNames(roles))
+	})
+}
+
+func TestGroupMembership(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newGroupService()
+
+	require.ErrorIs(t, svc.AddUserToGroup(ctx, "staff", "missing"), ErrUserNotFound)
+	require.ErrorIs(t, svc.AddUserToGroup(ctx, "missing", "alice"), ErrGroupNotFound)
+
+	require.NoError(t, svc.AddUserToGroup(ctx, "staff", "alice"))
+	members, err := svc.ListGroupMembers(ctx, "staff")
+	require.NoError(t, err)
+	require.Equal(t, []GroupMember{{GroupID: "staff", Kind: GroupMemberUser, MemberID: "alice"}}, members)
+
+	require.NoError(t, svc.RemoveUserFromGroup(ctx, "staff", "alice"))
+	members, err = svc.ListGroupMembers(ctx, "staff")
+	require.NoError(t, err)
+	require.Empty(t, members)
+
+	_, err = svc.GrantRoleToGroup(ctx, "staff", "missing")
+	require.ErrorIs(t, err, ErrRoleNotFound)
+
+	svc = NewService(&mockUserRepo{}, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+	_, err = svc.CreateGroup(ctx, Group{ID: "g"})
+	require.ErrorIs(t, err, ErrGroupsNotConfigured)
+}
+
+func TestGroupMembershipNeedsRoleAssign(t *testing.T) {
+	svc, _ := newGroupService()
+	svc.roleRepo.(*mockRoleRepo).roles["r-groups"] = &Role{ID: "r-groups", Name: "groups", Permissions: []string{PermissionGroupsManage}}
+	svc.userRepo.(*mockUserRepo).users["bob"].RoleID = "r-groups"
+	svc.enforceAuthz = true
+	require.NoError(t, svc.AddGroupToGroup(ContextWithSystemActor(context.Background()), "support", "emea"))
+	ctx := actorCtx("bob")
+
+	require.NoError(t, svc.AddUserToGroup(ctx, "staff", "bob"), "staff grants no roles")
+	require.ErrorIs(t, svc.AddUserToGroup(ctx, "support", "bob"), ErrForbidden)
+	require.ErrorIs(t, svc.AddUserToGroup(ctx, "emea", "bob"), ErrForbidden, "inherits roles from support")
+	require.ErrorIs(t, svc.AddGroupToGroup(ctx, "emea", "staff"), ErrForbidden)
+}
+
+func TestCreateGroupNeedsRoleAssign(t *testing.T) {
+	svc, _ := newGroupService()
+	svc.roleRepo.(*mockRoleRepo).roles["r-groups"] = &Role{ID: "r-groups", Name: "groups", Permissions: []string{PermissionGroupsManage}}
+	svc.userRepo.(*mockUserRepo).users["bob"].RoleID = "r-groups"
+	svc.enforceAuthz = true
+	ctx := actorCtx("bob")
+
+	_, err := svc.CreateGroup(ctx, Group{ID: "ops"})
+	require.NoError(t, err, "grants no roles")
+	_, err = svc.CreateGroup(ctx, Group{ID: "billing", RoleIDs: []string{"r-billing"}})
+	require.ErrorIs(t, err, ErrForbidden)
+}
@@ -0,0 +1,273 @@
+package users
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+// MinInvitationSecretLength is the shortest secret WithInvitations accepts.
+// Anyone who can guess the secret can forge invitation tokens.
+const MinInvitationSecretLength = 32
+
+type Invitation struct {
+	ID             string
+	OrganizationID string
+	Email          string
+	RoleID         string
+	InvitedBy      string
+	Status         InvitationStatus
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	AcceptedAt     *time.Time
+	AcceptedBy     string
+}
+
+// WithInvitations enables organization invitations. Tokens are signed with
+// secret and handed to notifier for delivery. secret must hold at least
+// MinInvitationSecretLength random bytes; with a shorter one every invitation
+// method fails with ErrInvitationsNotConfigured.
+func WithInvitations(repo InvitationRepository, notifier Notifier, secret []byte) ServiceOption {
+	return func(s *Service) {
+		s.invitationRepo = repo
+		s.notifier = notifier
+		s.invitationSecret = secret
+		if s.invitationTTL == 0 {
+			s.invitationTTL = DefaultInvitationTTL
+		}
+	}
+}
+
+func (s *Service) checkInvitationsConfigured() error {
+	if s.invitationRepo == nil || s.orgRepo == nil {
+		return ErrInvitationsNotConfigured
+	}
+	if len(s.invitationSecret) < MinInvitationSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d bytes", ErrInvitationsNotConfigured, MinInvitationSecretLength)
+	}
+	return nil
+}
+
+func WithInvitationTTL(ttl time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.invitationTTL = ttl
+	}
+}
+
+func (s *Service) InviteToOrganization(ctx context.Context, orgID, email, roleID string) (*Invitation, error) {
+	if err := s.checkInvitationsConfigured(); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeInOrganization(ctx, PermissionMembersManage, orgID); err != nil {
+		return nil, err
+	}
+	// Accepting grants the role without further checks, so it is assigned
+	// here.
+	if err := s.authorizeInOrganization(ctx, PermissionRolesAssign, orgID); err != nil {
+		return nil, err
+	}
+	// Normalized so that accepting finds the account however the address
+	// was capitalized.
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.orgRepo.GetByID(ctx, orgID); err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+	if _, err := s.roleRepo.GetByID(ctx, roleID); err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	actor, _ := ActorFromContext(ctx)
+	now := s.now()
+	invitation, err := s.invitationRepo.Create(ctx, Invitation{
+		OrganizationID: orgID,
+		Email:          email,
+		RoleID:         roleID,
+		InvitedBy:      actor.UserID,
+		Status:         InvitationPending,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.invitationTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToCreateInvitation, err)
+	}
+
+	token := s.signInvitationToken(invitation.ID, invitation.ExpiresAt)
+	if err := s.notifier.SendInvitation(ctx, *invitation, token); err != nil {
+		invitation.Status = InvitationRevoked
+		_, _ = s.invitationRepo.Update(ctx, *invitation)
+		return nil, fmt.Errorf("%w: %v", ErrFailedToSendInvitation, err)
+	}
+
+	if err := s.audit(ctx, AuditInvitationCreated, "invitation", invitation.ID, nil, invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// AcceptInvitation joins the invited email's account to the organization. When
+// no account exists yet, registration is used to create one; its Email is
+// always replaced with the invited address.
+func (s *Service) AcceptInvitation(ctx context.Context, token string, registration *UserRegisterInput) (*Membership, error) {
+	if err := s.checkInvitationsConfigured(); err != nil {
+		return nil, err
+	}
+
+	invitationID, expiresAt, err := s.parseInvitationToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !s.now().Before(expiresAt) {
+		return nil, ErrInvitationExpired
+	}
+
+	return inTx(ctx, s, func(ctx context.Context) (*Membership, error) {
+		invitation, err := s.invitationRepo.GetByID(ctx, invitationID)
+		if err != nil {
+			return nil, ErrInvalidInvitation
+		}
+		if invitation.Status != InvitationPending {
+			return nil, ErrInvitationNotPending
+		}
+
+		user, err := s.userRepo.GetByEmail(ctx, invitation.Email)
+		if err != nil || user == nil {
+			if registration == nil {
+				return nil, ErrUserNotFound
+			}
+			input := *registration
+			input.Email = invitation.Email
+			if user, err = s.Register(ctx, input); err != nil {
+				return nil, err
+			}
+		}
+
+		membership, err := s.addMembership(ctx, invitation.OrganizationID, user.ID, invitation.RoleID)
+		if errors.Is(err, ErrAlreadyMember) {
+			membership, err = s.orgRepo.GetMembership(ctx, invitation.OrganizationID, user.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		acceptedAt := s.now()
+		before := *invitation
+		invitation.Status = InvitationAccepted
+		invitation.AcceptedAt = &acceptedAt
+		invitation.AcceptedBy = user.ID
+		if _, err := s.invitationRepo.Update(ctx, *invitation); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToUpdateInvitation, err)
+		}
+
+		if err := s.audit(ctx, AuditInvitationAccepted, "invitation", invitation.ID, before, invitation); err != nil {
+			return nil, err
+		}
+		return membership, nil
+	})
+}
+
+func (s *Service) RevokeInvitation(ctx context.Context, invitationID string) error {
+	if err := s.checkInvitationsConfigured(); err != nil {
+		return err
+	}
+
+	invitation, err := s.invitationRepo.GetByID(ctx, invitationID)
+	if err != nil {
+		return ErrInvalidInvitation
+	}
+	if err := s.authorizeInOrganization(ctx, PermissionMembersManage, invitation.OrganizationID); err != nil {
+		return err
+	}
+	if invitation.Status != InvitationPending {
+		return ErrInvitationNotPending
+	}
+
+	before := *invitation
+	invitation.Status = InvitationRevoked
+	if _, err := s.invitationRepo.Update(ctx, *invitation); err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToUpdateInvitation, err)
+	}
+	return s.audit(ctx, AuditInvitationRevoked, "invitation", invitationID, before, invitation)
+}
+
+// ListPendingInvitations returns the organization's invitations that can still
+// be accepted.
+func (s *Service) ListPendingInvitations(ctx context.Context, orgID string) ([]Invitation, error) {
+	if err := s.checkInvitationsConfigured(); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeInOrganization(ctx, PermissionMembersManage, orgID); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.invitationRepo.ListPending(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var pending []Invitation
+	for _, invitation := range invitations {
+		if invitation.Status == InvitationPending && now.Before(invitation.ExpiresAt) {
+			pending = append(pending, invitation)
+		}
+	}
+	return pending, nil
+}
+
+// Invitation tokens have the form base64(id|expiry).base64(hmac).
+func (s *Service) signInvitationToken(invitationID string, expiresAt time.Time) string {
+	payload := invitationID + "|" + strconv.FormatInt(expiresAt.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.invitationMAC(payload))
+}
+
+func (s *Service) parseInvitationToken(token string) (string, time.Time, error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", time.Time{}, ErrInvalidInvitation
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidInvitation
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.invitationMAC(string(payload))) {
+		return "", time.Time{}, ErrInvalidInvitation
+	}
+
+	invitationID, expiry, ok := strings.Cut(string(payload), "|")
+	if !ok {
+		return "", time.Time{}, ErrInvalidInvitation
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidInvitation
+	}
+	return invitationID, time.Unix(unix, 0), nil
+}
+
+func (s *Service) invitationMAC(payload string) []byte {
+	mac := hmac.New(sha256.New, s.invitationSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
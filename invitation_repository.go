@@ -0,0 +1,10 @@
+package users
+
+import "context"
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation Invitation) (*Invitation, error)
+	Update(ctx context.Context, invitation Invitation) (*Invitation, error)
+	GetByID(ctx context.Context, id string) (*Invitation, error)
+	ListPending(ctx context.Context, orgID string) ([]Invitation, error)
+}
@@ -0,0 +1,209 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockInvitationRepo struct {
+	invitations map[string]*Invitation
+	nextID      int
+}
+
+func (m *mockInvitationRepo) Create(ctx context.Context, inv Invitation) (*Invitation, error) {
+	m.nextID++
+	inv.ID = fmt.Sprintf("inv%d", m.nextID)
+	m.invitations[inv.ID] = &inv
+	copied := inv
+	return &copied, nil
+}
+func (m *mockInvitationRepo) Update(ctx context.Context, inv Invitation) (*Invitation, error) {
+	m.invitations[inv.ID] = &inv
+	return &inv, nil
+}
+func (m *mockInvitationRepo) GetByID(ctx context.Context, id string) (*Invitation, error) {
+	inv, ok := m.invitations[id]
+	if !ok {
+		return nil, ErrInvalidInvitation
+	}
+	copied := *inv
+	return &copied, nil
+}
+func (m *mockInvitationRepo) ListPending(ctx context.Context, orgID string) ([]Invitation, error) {
+	var invs []Invitation
+	for _, inv := range m.invitations {
+		if inv.OrganizationID == orgID && inv.Status == InvitationPending {
+			invs = append(invs, *inv)
+		}
+	}
+	return invs, nil
+}
+
+type mockNotifier struct {
+	tokens  map[string]string
+	sendErr error
+}
+
+func (m *mockNotifier) SendInvitation(ctx context.Context, inv Invitation, token string) error {
+	if m.sendErr != nil {
+		return m.sendErr
+	}
+	m.tokens[inv.Email] = token
+	return nil
+}
+
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newInvitationService() (*Service, *mockNotifier, *testClock, *mockOrganizationRepo) {
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"alice": {ID: "alice", Email: "alice@example.com", RoleID: "r-user"},
+	}}
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{
+		"r-user":   {ID: "r-user", Name: RoleUser},
+		"r-editor": {ID: "r-editor", Name: "editor"},
+	}}
+	orgRepo := newMockOrganizationRepo()
+	orgRepo.orgs["acme"] = &Organization{ID: "acme", Name: "Acme"}
+	notifier := &mockNotifier{tokens: map[string]string{}}
+	clock := &testClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{},
+		WithClock(clock.Now),
+		WithOrganizationRepository(orgRepo),
+		WithInvitations(&mockInvitationRepo{invitations: map[string]*Invitation{}}, notifier, []byte(testInvitationSecret)),
+	)
+	return svc, notifier, clock, orgRepo
+}
+
+const testInvitationSecret = "0123456789abcdef0123456789abcdef"
+
+func TestInvitations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("existing user is linked", func(t *testing.T) {
+		svc, notifier, _, _ := newInvitationService()
+		inv, err := svc.InviteToOrganization(ctx, "acme", "alice@example.com", "r-editor")
+		require.NoError(t, err)
+		require.Equal(t, InvitationPending, inv.Status)
+
+		m, err := svc.AcceptInvitation(ctx, notifier.tokens["alice@example.com"], nil)
+		require.NoError(t, err)
+		require.Equal(t, "alice", m.UserID)
+		require.Equal(t, "r-editor", m.RoleID)
+
+		_, err = svc.AcceptInvitation(ctx, notifier.tokens["alice@example.com"], nil)
+		require.ErrorIs(t, err, ErrInvitationNotPending)
+	})
+
+	t.Run("new user registers", func(t *testing.T) {
+		svc, notifier, _, orgRepo := newInvitationService()
+		_, err := svc.InviteToOrganization(ctx, "acme", "new@example.com", "r-editor")
+		require.NoError(t, err)
+		token := notifier.tokens["new@example.com"]
+
+		_, err = svc.AcceptInvitation(ctx, token, nil)
+		require.ErrorIs(t, err, ErrUserNotFound)
+
+		m, err := svc.AcceptInvitation(ctx, token, &UserRegisterInput{Email: "other@example.com", Username: "new", Password: "pw"})
+		require.NoError(t, err)
+		require.Equal(t, "r-editor", m.RoleID)
+
+		u, err := svc.userRepo.GetByEmail(ctx, "new@example.com")
+		require.NoError(t, err)
+		require.Equal(t, "new", u.Username)
+		require.Len(t, orgRepo.memberships, 1)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		svc, notifier, clock, _ := newInvitationService()
+		_, err := svc.InviteToOrganization(ctx, "acme", "alice@example.com", "r-editor")
+		require.NoError(t, err)
+
+		clock.now = clock.now.Add(DefaultInvitationTTL + time.Second)
+		_, err = svc.AcceptInvitation(ctx, notifier.tokens["alice@example.com"], nil)
+		require.ErrorIs(t, err, ErrInvitationExpired)
+
+		pending, err := svc.ListPendingInvitations(ctx, "acme")
+		require.NoError(t, err)
+		require.Empty(t, pending)
+	})
+
+	t.Run("tampered token", func(t *testing.T) {
+		svc, notifier, _, _ := newInvitationService()
+		_, err := svc.InviteToOrganization(ctx, "acme", "alice@example.com", "r-editor")
+		require.NoError(t, err)
+
+		other := NewService(svc.userRepo, svc.roleRepo, &mockHasher{}, &mockTokenizer{},
+			WithOrganizationRepository(svc.orgRepo),
+			WithInvitations(svc.invitationRepo, notifier, []byte(strings.Repeat("w", MinInvitationSecretLength))))
+		_, err = other.AcceptInvitation(ctx, notifier.tokens["alice@example.com"], nil)
+		require.ErrorIs(t, err, ErrInvalidInvitation)
+
+		_, err = svc.AcceptInvitation(ctx, "garbage", nil)
+		require.ErrorIs(t, err, ErrInvalidInvitation)
+	})
+
+	t.Run("short secret", func(t *testing.T) {
+		svc, notifier, _, _ := newInvitationService()
+		for _, secret := range [][]byte{nil, []byte("secret")} {
+			weak := NewService(svc.userRepo, svc.roleRepo, &mockHasher{}, &mockTokenizer{},
+				WithOrganizationRepository(svc.orgRepo),
+				WithInvitations(svc.invitationRepo, notifier, secret))
+			_, err := weak.InviteToOrganization(ctx, "acme", "alice@example.com", "r-editor")
+			require.ErrorIs(t, err, ErrInvitationsNotConfigured)
+			_, err = weak.AcceptInvitation(ctx, "forged.token", nil)
+			require.ErrorIs(t, err, ErrInvitationsNotConfigured)
+		}
+	})
+
+	t.Run("revoke and list pending", func(t *testing.T) {
+		svc, notifier, _, _ := newInvitationService()
+		inv, err := svc.InviteToOrganization(ctx, "acme", "alice@example.com", "r-editor")
+		require.NoError(t, err)
+		_, err = svc.InviteToOrganization(ctx, "acme", "bob@example.com", "r-user")
+		require.NoError(t, err)
+
+		pending, err := svc.ListPendingInvitations(ctx, "acme")
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+
+		require.NoError(t, svc.RevokeInvitation(ctx, inv.ID))
+		require.ErrorIs(t, svc.RevokeInvitation(ctx, inv.ID), ErrInvitationNotPending)
+
+		pending, err = svc.ListPendingInvitations(ctx, "acme")
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+
+		_, err = svc.AcceptInvitation(ctx, notifier.tokens["alice@example.com"], nil)
+		require.ErrorIs(t, err, ErrInvitationNotPending)
+	})
+
+	t.Run("delivery failure", func(t *testing.T) {
+		svc, notifier, _, _ := newInvitationService()
+		notifier.sendErr = errors.New("smtp down")
+		_, err := svc.InviteToOrganization(ctx, "acme", "alice@example.com", "r-editor")
+		require.ErrorIs(t, err, ErrFailedToSendInvitation)
+
+		pending, err := svc.ListPendingInvitations(ctx, "acme")
+		require.NoError(t, err)
+		require.Empty(t, pending)
+	})
+
+	t.Run("member manager cannot pick roles", func(t *testing.T) {
+		svc, notifier, _, orgRepo := newInvitationService()
+		svc.enforceAuthz = true
+		svc.roleRepo.(*mockRoleRepo).roles["r-members"] = &Role{ID: "r-members", Name: "members", Permissions: []string{PermissionMembersManage}}
+		orgRepo.memberships[membershipKey("acme", "alice")] = &Membership{OrganizationID: "acme", UserID: "alice", RoleID: "r-members"}
+
+		_, err := svc.InviteToOrganization(actorCtx("alice"), "acme", "bob@example.com", "r-editor")
+		require.ErrorIs(t, err, ErrForbidden)
+		require.Empty(t, notifier.tokens)
+	})
+}
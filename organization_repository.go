@@ -0,0 +1,19 @@
+package users
+
+import "context"
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, org Organization) (*Organization, error)
+	Update(ctx context.Context, org Organization) (*Organization, error)
+	Delete(ctx context.Context, id string) error
+	GetByID(ctx context.Context, id string) (*Organization, error)
+	GetBySlug(ctx context.Context, slug string) (*Organization, error)
+	List(ctx context.Context) ([]Organization, error)
+
+	AddMembership(ctx context.Context, membership Membership) (*Membership, error)
+	UpdateMembership(ctx context.Context, membership Membership) (*Membership, error)
+	RemoveMembership(ctx context.Context, orgID, userID string) error
+	GetMembership(ctx context.Context, orgID, userID string) (*Membership, error)
+	ListMemberships(ctx context.Context, orgID string) ([]Membership, error)
+	ListUserMemberships(ctx context.Context, userID string) ([]Membership, error)
+}
@@ -0,0 +1,218 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockOrganizationRepo struct {
+	orgs        map[string]*Organization
+	memberships map[string]*Membership
+	createErr   error
+}
+
+func newMockOrganizationRepo() *mockOrganizationRepo {
+	return &mockOrganizationRepo{orgs: map[string]*Organization{}, memberships: map[string]*Membership{}}
+}
+
+func membershipKey(orgID, userID string) string { return orgID + "/" + userID }
+
+func (m *mockOrganizationRepo) Create(ctx context.Context, org Organization) (*Organization, error) {
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+	m.orgs[org.ID] = &org
+	return &org, nil
+}
+func (m *mockOrganizationRepo) Update(ctx context.Context, org Organization) (*Organization, error) {
+	m.orgs[org.ID] = &org
+	return &org, nil
+}
+func (m *mockOrganizationRepo) Delete(ctx context.Context, id string) error {
+	delete(m.orgs, id)
+	return nil
+}
+func (m *mockOrganizationRepo) GetByID(ctx context.Context, id string) (*Organization, error) {
+	o, ok := m.orgs[id]
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	return o, nil
+}
+func (m *mockOrganizationRepo) GetBySlug(ctx context.Context, slug string) (*Organization, error) {
+	for _, o := range m.orgs {
+		if o.Slug == slug {
+			return o, nil
+		}
+	}
+	return nil, ErrOrganizationNotFound
+}
+func (m *mockOrganizationRepo) List(ctx context.Context) ([]Organization, error) {
+	var os []Organization
+	for _, o := range m.orgs {
+		os = append(os, *o)
+	}
+	return os, nil
+}
+func (m *mockOrganizationRepo) AddMembership(ctx context.Context, ms Membership) (*Membership, error) {
+	m.memberships[membershipKey(ms.OrganizationID, ms.UserID)] = &ms
+	return &ms, nil
+}
+func (m *mockOrganizationRepo) UpdateMembership(ctx context.Context, ms Membership) (*Membership, error) {
+	m.memberships[membershipKey(ms.OrganizationID, ms.UserID)] = &ms
+	return &ms, nil
+}
+func (m *mockOrganizationRepo) RemoveMembership(ctx context.Context, orgID, userID string) error {
+	delete(m.memberships, membershipKey(orgID, userID))
+	return nil
+}
+func (m *mockOrganizationRepo) GetMembership(ctx context.Context, orgID, userID string) (*Membership, error) {
+	ms, ok := m.memberships[membershipKey(orgID, userID)]
+	if !ok {
+		return nil, ErrMembershipNotFound
+	}
+	copied := *ms
+	return &copied, nil
+}
+func (m *mockOrganizationRepo) ListMemberships(ctx context.Context, orgID string) ([]Membership, error) {
+	var ms []Membership
+	for _, membership := range m.memberships {
+		if membership.OrganizationID == orgID {
+			ms = append(ms, *membership)
+		}
+	}
+	return ms, nil
+}
+func (m *mockOrganizationRepo) ListUserMemberships(ctx context.Context, userID string) ([]Membership, error) {
+	var ms []Membership
+	for _, membership := range m.memberships {
+		if membership.UserID == userID {
+			ms = append(ms, *membership)
+		}
+	}
+	return ms, nil
+}
+
+func newOrganizationService() (*Service, *mockOrganizationRepo) {
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"alice": {ID: "alice", Email: "alice@example.com", RoleID: "r-user"},
+		"bob":   {ID: "bob", Email: "bob@example.com", RoleID: "r-user"},
+	}}
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{
+		"r-admin": {ID: "r-admin", Name: RoleAdmin},
+		"r-user":  {ID: "r-user", Name: RoleUser},
+	}}
+	orgRepo := newMockOrganizationRepo()
+	orgRepo.orgs["acme"] = &Organization{ID: "acme", Name: "Acme"}
+	orgRepo.orgs["globex"] = &Organization{ID: "globex", Name: "Globex"}
+	return NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithOrganizationRepository(orgRepo)), orgRepo
+}
+
+func TestOrganizationMemberships(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newOrganizationService()
+
+	t.Run("different roles per organization", func(t *testing.T) {
+		_, err := svc.AddUserToOrganization(ctx, "acme", "alice", "r-admin")
+		require.NoError(t, err)
+		_, err = svc.AddUserToOrganization(ctx, "globex", "alice", "r-user")
+		require.NoError(t, err)
+
+		ms, err := svc.ListUserMemberships(ctx, "alice")
+		require.NoError(t, err)
+		roles := map[string]string{}
+		for _, m := range ms {
+			roles[m.OrganizationID] = m.RoleID
+		}
+		require.Equal(t, map[string]string{"acme": "r-admin", "globex": "r-user"}, roles)
+	})
+
+	t.Run("already member", func(t *testing.T) {
+		_, err := svc.AddUserToOrganization(ctx, "acme", "alice", "r-user")
+		require.ErrorIs(t, err, ErrAlreadyMember)
+	})
+
+	t.Run("unknown organization, user and role", func(t *testing.T) {
+		_, err := svc.AddUserToOrganization(ctx, "nope", "bob", "r-user")
+		require.ErrorIs(t, err, ErrOrganizationNotFound)
+		_, err = svc.AddUserToOrganization(ctx, "acme", "nope", "r-user")
+		require.ErrorIs(t, err, ErrUserNotFound)
+		_, err = svc.AddUserToOrganization(ctx, "acme", "bob", "nope")
+		require.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("list organization users", func(t *testing.T) {
+		_, err := svc.AddUserToOrganization(ctx, "acme", "bob", "r-user")
+		require.NoError(t, err)
+		us, err := svc.ListOrganizationUsers(ctx, "acme")
+		require.NoError(t, err)
+		require.Len(t, us, 2)
+		us, err = svc.ListOrganizationUsers(ctx, "globex")
+		require.NoError(t, err)
+		require.Len(t, us, 1)
+	})
+
+	t.Run("assign organization role", func(t *testing.T) {
+		m, err := svc.AssignOrganizationRoleToUser(ctx, "globex", "alice", "r-admin")
+		require.NoError(t, err)
+		require.Equal(t, "r-admin", m.RoleID)
+
+		u, err := svc.GetUserByID(ctx, "alice")
+		require.NoError(t, err)
+		require.Equal(t, "r-user", u.RoleID)
+
+		_, err = svc.AssignOrganizationRoleToUser(ctx, "globex", "bob", "r-admin")
+		require.ErrorIs(t, err, ErrMembershipNotFound)
+	})
+
+	t.Run("remove member", func(t *testing.T) {
+		require.NoError(t, svc.RemoveUserFromOrganization(ctx, "acme", "bob"))
+		require.ErrorIs(t, svc.RemoveUserFromOrganization(ctx, "acme", "bob"), ErrMembershipNotFound)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		svc := NewService(&mockUserRepo{}, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.ListOrganizationUsers(ctx, "acme")
+		require.ErrorIs(t, err, ErrOrganizationsNotConfigured)
+	})
+}
+
+func TestOrganizationAuthorization(t *testing.T) {
+	svc, orgRepo := newOrganizationService()
+	svc.enforceAuthz = true
+	orgRepo.memberships[membershipKey("acme", "alice")] = &Membership{OrganizationID: "acme", UserID: "alice", RoleID: "r-admin"}
+	orgRepo.memberships[membershipKey("globex", "alice")] = &Membership{OrganizationID: "globex", UserID: "alice", RoleID: "r-user"}
+
+	_, err := svc.AddUserToOrganization(actorCtx("alice"), "acme", "bob", "r-user")
+	require.NoError(t, err)
+
+	_, err = svc.AddUserToOrganization(actorCtx("alice"), "globex", "bob", "r-user")
+	require.ErrorIs(t, err, ErrForbidden)
+
+	_, err = svc.ListOrganizationUsers(actorCtx("bob"), "acme")
+	require.ErrorIs(t, err, ErrForbidden)
+
+	t.Run("member manager cannot pick roles", func(t *testing.T) {
+		svc.roleRepo.(*mockRoleRepo).roles["r-members"] = &Role{ID: "r-members", Name: "members", Permissions: []string{PermissionMembersManage}}
+		orgRepo.memberships[membershipKey("globex", "bob")] = &Membership{OrganizationID: "globex", UserID: "bob", RoleID: "r-members"}
+		_, err := svc.AddUserToOrganization(actorCtx("bob"), "globex", "alice", "r-admin")
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("restricted org admin", func(t *testing.T) {
+		alice := svc.userRepo.(*mockUserRepo).users["alice"]
+		alice.Status = StatusBanned
+		_, err := svc.AddUserToOrganization(actorCtx("alice"), "acme", "carol", "r-user")
+		require.ErrorIs(t, err, ErrForbidden)
+
+		alice.Status = StatusActive
+		deletedAt := time.Now()
+		alice.DeletedAt = &deletedAt
+		err = svc.RemoveUserFromOrganization(actorCtx("alice"), "acme", "bob")
+		require.ErrorIs(t, err, ErrForbidden)
+		require.Contains(t, orgRepo.memberships, membershipKey("acme", "bob"))
+	})
+}
@@ -0,0 +1,202 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	OutboxDead      OutboxStatus = "dead"
+)
+
+// OutboxMessage is a serialized event waiting to be relayed. It satisfies
+// Event itself, so subscribers receive it as-is and use Decode to recover
+// the concrete event.
+type OutboxMessage struct {
+	ID            string
+	Type          string
+	UserID        string
+	Payload       json.RawMessage
+	CreatedAt     time.Time
+	Status        OutboxStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+func (m OutboxMessage) EventType() string     { return m.Type }
+func (m OutboxMessage) AggregateID() string   { return m.UserID }
+func (m OutboxMessage) OccurredAt() time.Time { return m.CreatedAt }
+
+// Decode unmarshals the payload into v, typically a pointer to the event
+// struct matching Type.
+func (m OutboxMessage) Decode(v any) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+// WithOutbox makes Service write events to repo instead of publishing them
+// directly. An OutboxRelay then delivers them to the EventPublisher.
+func WithOutbox(repo OutboxRepository) ServiceOption {
+	return func(s *Service) {
+		s.outbox = repo
+	}
+}
+
+func (s *Service) appendToOutbox(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToWriteOutbox, err)
+	}
+
+	err = s.outbox.Append(ctx, OutboxMessage{
+		Type:      event.EventType(),
+		UserID:    event.AggregateID(),
+		Payload:   payload,
+		CreatedAt: event.OccurredAt(),
+		Status:    OutboxPending,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToWriteOutbox, err)
+	}
+	return nil
+}
+
+type OutboxRelayConfig struct {
+	BatchSize      int
+	PollInterval   time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Clock          func() time.Time
+}
+
+// OutboxRelay delivers outbox messages to an EventPublisher at least once.
+// Messages for the same user are delivered in the order they were written: a
+// message waiting for a retry holds back the later messages of its user.
+// After MaxAttempts failures a message is moved to the dead-letter state and
+// no longer blocks its user.
+type OutboxRelay struct {
+	repo      OutboxRepository
+	publisher EventPublisher
+	cfg       OutboxRelayConfig
+}
+
+func NewOutboxRelay(repo OutboxRepository, publisher EventPublisher, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return &OutboxRelay{repo: repo, publisher: publisher, cfg: cfg}
+}
+
+// Run polls the outbox until ctx is cancelled. It is meant to be started in
+// its own goroutine.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Storage errors are transient from the relay's point of view; the
+		// next tick tries again.
+		_, _ = r.DeliverPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverPending makes one pass over the pending messages that are due and
+// returns how many were delivered. Messages are read BatchSize at a time and
+// the pass keeps going past users whose messages wait for a retry, so those
+// users cannot hold back everybody else.
+func (r *OutboxRelay) DeliverPending(ctx context.Context) (int, error) {
+	now := r.cfg.Clock()
+	blocked := map[string]bool{}
+	delivered := 0
+	after := ""
+	for {
+		messages, err := r.repo.ListByStatus(ctx, OutboxPending, after, r.cfg.BatchSize)
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, msg := range messages {
+			after = msg.ID
+			if blocked[msg.UserID] {
+				continue
+			}
+			if msg.NextAttemptAt.After(now) {
+				blocked[msg.UserID] = true
+				continue
+			}
+
+			if err := r.publisher.Publish(ctx, msg); err != nil {
+				msg.Attempts++
+				msg.LastError = err.Error()
+				if msg.Attempts >= r.cfg.MaxAttempts {
+					msg.Status = OutboxDead
+				} else {
+					msg.NextAttemptAt = now.Add(exponentialBackoff(r.cfg.InitialBackoff, r.cfg.MaxBackoff, msg.Attempts))
+					blocked[msg.UserID] = true
+				}
+			} else {
+				msg.Attempts++
+				msg.Status = OutboxDelivered
+				msg.LastError = ""
+				delivered++
+			}
+
+			if err := r.repo.Update(ctx, msg); err != nil {
+				return delivered, err
+			}
+		}
+		if len(messages) < r.cfg.BatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// Requeue returns a dead-lettered message to the pending state with a fresh
+// attempt budget.
+func (r *OutboxRelay) Requeue(ctx context.Context, msg OutboxMessage) error {
+	msg.Status = OutboxPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = time.Time{}
+	return r.repo.Update(ctx, msg)
+}
+
+// exponentialBackoff doubles initial after every failed attempt, up to
+// ceiling.
+func exponentialBackoff(initial, ceiling time.Duration, attempts int) time.Duration {
+	d := initial
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= ceiling {
+			return ceiling
+		}
+	}
+	return min(d, ceiling)
+}
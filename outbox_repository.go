@@ -0,0 +1,92 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+type OutboxRepository interface {
+	// Append stores a pending message and assigns its ID. Adapters should
+	// enlist the write in the transaction carried by ctx so it commits or
+	// rolls back together with the user change that produced it.
+	Append(ctx context.Context, msg OutboxMessage) error
+	// ListByStatus returns up to limit messages with status in append order,
+	// starting after the message with ID after, or with the oldest when after
+	// is empty. A limit of zero means no limit.
+	ListByStatus(ctx context.Context, status OutboxStatus, after string, limit int) ([]OutboxMessage, error)
+	Update(ctx context.Context, msg OutboxMessage) error
+}
+
+// MemoryOutbox is an OutboxRepository kept in process memory.
+type MemoryOutbox struct {
+	mu       sync.Mutex
+	messages []OutboxMessage
+	seq      int
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+func (m *MemoryOutbox) Append(ctx context.Context, msg OutboxMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seq++
+	msg.ID = strconv.Itoa(m.seq)
+	m.messages = append(m.messages, msg)
+	onRollback(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.messages = slices.DeleteFunc(m.messages, func(queued OutboxMessage) bool {
+			return queued.ID == msg.ID
+		})
+	})
+	return nil
+}
+
+func (m *MemoryOutbox) ListByStatus(ctx context.Context, status OutboxStatus, after string, limit int) ([]OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found []OutboxMessage
+	started := after == ""
+	for _, msg := range m.messages {
+		if !started {
+			started = msg.ID == after
+			continue
+		}
+		if msg.Status != status {
+			continue
+		}
+		if limit > 0 && len(found) == limit {
+			break
+		}
+		found = append(found, msg)
+	}
+	return found, nil
+}
+
+func (m *MemoryOutbox) Update(ctx context.Context, msg OutboxMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.messages {
+		if m.messages[i].ID == msg.ID {
+			previous := m.messages[i]
+			m.messages[i] = msg
+			onRollback(ctx, func() {
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				if j := slices.IndexFunc(m.messages, func(queued OutboxMessage) bool { return queued.ID == msg.ID }); j >= 0 {
+					m.messages[j] = previous
+				}
+			})
+			return nil
+		}
+	}
+	return fmt.Errorf("outbox message %s not found", msg.ID)
+}
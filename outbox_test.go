@@ -0,0 +1,162 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingOutbox struct {
+	MemoryOutbox
+}
+
+func (f *failingOutbox) Append(ctx context.Context, msg OutboxMessage) error {
+	return errors.New("disk full")
+}
+
+func TestServiceWritesOutbox(t *testing.T) {
+	ctx := actorCtx("admin")
+	userRepo := &mockUserRepo{users: map[string]*User{"alice": {ID: "alice", HashedPassword: "hashed:pw"}}}
+
+	t.Run("events are appended instead of published", func(t *testing.T) {
+		outbox := NewMemoryOutbox()
+		events := &recordingPublisher{}
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{},
+			WithEventPublisher(events), WithOutbox(outbox))
+
+		_, err := svc.ResetPassword(ctx, "alice", "pw2")
+		require.NoError(t, err)
+		require.Empty(t, events.events)
+
+		pending, err := outbox.ListByStatus(ctx, OutboxPending, "", 0)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		require.Equal(t, EventPasswordChanged, pending[0].Type)
+		require.Equal(t, "alice", pending[0].UserID)
+
+		var event PasswordChanged
+		require.NoError(t, pending[0].Decode(&event))
+		require.True(t, event.Reset)
+		require.Equal(t, "admin", event.ActorID)
+	})
+
+	t.Run("outbox failure fails the mutation", func(t *testing.T) {
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{}, WithOutbox(&failingOutbox{}))
+		_, err := svc.ResetPassword(ctx, "alice", "pw3")
+		require.ErrorIs(t, err, ErrFailedToWriteOutbox)
+	})
+}
+
+type flakyPublisher struct {
+	fail      map[string]int
+	delivered []string
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event Event) error {
+	msg := event.(OutboxMessage)
+	if p.fail[msg.ID] > 0 {
+		p.fail[msg.ID]--
+		return errors.New("unavailable")
+	}
+	p.delivered = append(p.delivered, msg.ID)
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	clock := &testClock{now: now}
+
+	outbox := NewMemoryOutbox()
+	for _, userID := range []string{"alice", "bob", "alice"} {
+		require.NoError(t, outbox.Append(ctx, OutboxMessage{Type: EventUserUpdated, UserID: userID, Status: OutboxPending}))
+	}
+	// Message 1 is alice's first event; it fails twice before succeeding.
+	publisher := &flakyPublisher{fail: map[string]int{"1": 2}}
+	relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		Clock:          clock.Now,
+	})
+
+	n, err := relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []string{"2"}, publisher.delivered, "alice's second event waits behind her first")
+
+	n, err = relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n, "retry is not due yet")
+
+	clock.now = now.Add(time.Second)
+	_, err = relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2"}, publisher.delivered)
+
+	clock.now = now.Add(3 * time.Second)
+	n, err = relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{"2", "1", "3"}, publisher.delivered)
+
+	delivered, err := outbox.ListByStatus(ctx, OutboxDelivered, "", 0)
+	require.NoError(t, err)
+	require.Len(t, delivered, 3)
+	require.Equal(t, 3, delivered[0].Attempts)
+}
+
+func TestOutboxRelayDoesNotStarveOtherUsers(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox()
+	for _, userID := range []string{"alice", "alice", "alice", "bob"} {
+		require.NoError(t, outbox.Append(ctx, OutboxMessage{Type: EventUserUpdated, UserID: userID, Status: OutboxPending}))
+	}
+	publisher := &flakyPublisher{fail: map[string]int{"1": 100}}
+	relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{BatchSize: 3})
+
+	n, err := relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []string{"4"}, publisher.delivered)
+}
+
+func TestOutboxRelayDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox()
+	require.NoError(t, outbox.Append(ctx, OutboxMessage{Type: EventUserUpdated, UserID: "alice", Status: OutboxPending}))
+	require.NoError(t, outbox.Append(ctx, OutboxMessage{Type: EventUserDeleted, UserID: "alice", Status: OutboxPending}))
+	publisher := &flakyPublisher{fail: map[string]int{"1": 100}}
+	relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{MaxAttempts: 1})
+
+	_, err := relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	_, err = relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2"}, publisher.delivered)
+
+	dead, err := outbox.ListByStatus(ctx, OutboxDead, "", 0)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	require.Equal(t, "unavailable", dead[0].LastError)
+
+	publisher.fail["1"] = 0
+	require.NoError(t, relay.Requeue(ctx, dead[0]))
+	_, err = relay.DeliverPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2", "1"}, publisher.delivered)
+}
+
+func TestOutboxRelayRun(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	require.NoError(t, outbox.Append(context.Background(), OutboxMessage{UserID: "alice", Status: OutboxPending}))
+	publisher := &flakyPublisher{}
+	relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{PollInterval: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, relay.Run(ctx), context.DeadlineExceeded)
+	require.Equal(t, []string{"1"}, publisher.delivered)
+}
@@ -0,0 +1,406 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type PolicyEffect string
+
+const (
+	PolicyAllow PolicyEffect = "allow"
+	PolicyDeny  PolicyEffect = "deny"
+)
+
+const (
+	OpEquals      = "eq"
+	OpNotEquals   = "ne"
+	OpIn          = "in"
+	OpNotIn       = "not_in"
+	OpContains    = "contains"
+	OpExists      = "exists"
+	OpGreaterThan = "gt"
+	OpLessThan    = "lt"
+)
+
+// Policy is a declarative set of attribute-based access rules. Deny rules
+// take precedence over allow rules, and a request no rule matches is denied.
+type Policy struct {
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// PolicyRule applies its effect when the action and resource type match and
+// every condition holds. Actions and resources accept "*" and prefix
+// wildcards such as "users:*".
+type PolicyRule struct {
+	ID          string            `json:"id" yaml:"id"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Effect      PolicyEffect      `json:"effect" yaml:"effect"`
+	Actions     []string          `json:"actions" yaml:"actions"`
+	Resources   []string          `json:"resources" yaml:"resources"`
+	Conditions  []PolicyCondition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+// PolicyCondition compares the attribute at a path such as "subject.region"
+// or "resource.owner_id" against a literal Value or, when ValueFrom is set,
+// against another attribute path.
+type PolicyCondition struct {
+	Attribute string `json:"attribute" yaml:"attribute"`
+	Operator  string `json:"operator" yaml:"operator"`
+	Value     any    `json:"value,omitempty" yaml:"value,omitempty"`
+	ValueFrom string `json:"value_from,omitempty" yaml:"value_from,omitempty"`
+}
+
+type Resource struct {
+	Type       string
+	ID         string
+	Attributes map[string]any
+}
+
+// UserResource describes a user account as a policy resource. The user's
+// Attributes cannot override "owner_id".
+func UserResource(user User) Resource {
+	attributes := map[string]any{}
+	for key, value := range user.Attributes {
+		attributes[key] = value
+	}
+	attributes["owner_id"] = user.ID
+	return Resource{Type: "user", ID: user.ID, Attributes: attributes}
+}
+
+type AccessRequest struct {
+	Subject     map[string]any
+	Action      string
+	Resource    Resource
+	Environment map[string]any
+}
+
+// PolicyDecision reports the outcome of an evaluation. Rule is the rule that
+// decided it, or nil when the request was denied because nothing matched.
+type PolicyDecision struct {
+	Allowed bool
+	Rule    *PolicyRule
+}
+
+type PolicyEngine struct {
+	policy Policy
+}
+
+func NewPolicyEngine(policy Policy) (*PolicyEngine, error) {
+	for i, rule := range policy.Rules {
+		if rule.Effect != PolicyAllow && rule.Effect != PolicyDeny {
+			return nil, fmt.Errorf("%w: rule %d has unknown effect %q", ErrInvalidPolicy, i, rule.Effect)
+		}
+		if len(rule.Actions) == 0 || len(rule.Resources) == 0 {
+			return nil, fmt.Errorf("%w: rule %d needs at least one action and resource", ErrInvalidPolicy, i)
+		}
+		for _, cond := range rule.Conditions {
+			switch cond.Operator {
+			case OpEquals, OpNotEquals, OpIn, OpNotIn, OpContains, OpExists, OpGreaterThan, OpLessThan:
+			default:
+				return nil, fmt.Errorf("%w: rule %d has unknown operator %q", ErrInvalidPolicy, i, cond.Operator)
+			}
+		}
+	}
+	return &PolicyEngine{policy: policy}, nil
+}
+
+func ParsePolicyJSON(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	return &policy, nil
+}
+
+func ParsePolicyYAML(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	return &policy, nil
+}
+
+// LoadPolicyFile reads a JSON or YAML policy, picking the format from the
+// file extension.
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParsePolicyJSON(data)
+	case ".yaml", ".yml":
+		return ParsePolicyYAML(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported policy file %s", ErrInvalidPolicy, path)
+	}
+}
+
+func (e *PolicyEngine) Evaluate(req AccessRequest) PolicyDecision {
+	var allowed *PolicyRule
+	for i := range e.policy.Rules {
+		rule := &e.policy.Rules[i]
+		if !rule.matches(req) {
+			continue
+		}
+		if rule.Effect == PolicyDeny {
+			return PolicyDecision{Allowed: false, Rule: rule}
+		}
+		if allowed == nil {
+			allowed = rule
+		}
+	}
+	if allowed != nil {
+		return PolicyDecision{Allowed: true, Rule: allowed}
+	}
+	return PolicyDecision{Allowed: false}
+}
+
+func (r *PolicyRule) matches(req AccessRequest) bool {
+	if !matchesAnyPattern(r.Actions, req.Action) || !matchesAnyPattern(r.Resources, req.Resource.Type) {
+		return false
+	}
+	for _, cond := range r.Conditions {
+		if !cond.holds(req) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == value {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c PolicyCondition) holds(req AccessRequest) bool {
+	actual, found := req.attribute(c.Attribute)
+	if c.Operator == OpExists {
+		return found
+	}
+	if !found {
+		return false
+	}
+
+	expected := c.Value
+	if c.ValueFrom != "" {
+		var ok bool
+		if expected, ok = req.attribute(c.ValueFrom); !ok {
+			return false
+		}
+	}
+
+	switch c.Operator {
+	case OpEquals:
+		return valuesEqual(actual, expected)
+	case OpNotEquals:
+		return !valuesEqual(actual, expected)
+	case OpIn:
+		return listContains(expected, actual)
+	case OpNotIn:
+		return !listContains(expected, actual)
+	case OpContains:
+		return listContains(actual, expected)
+	case OpGreaterThan:
+		cmp, ok := compareValues(actual, expected)
+		return ok && cmp > 0
+	case OpLessThan:
+		cmp, ok := compareValues(actual, expected)
+		return ok && cmp < 0
+	}
+	return false
+}
+
+func (req AccessRequest) attribute(path string) (any, bool) {
+	if path == "action" {
+		return req.Action, true
+	}
+
+	scope, key, _ := strings.Cut(path, ".")
+	switch scope {
+	case "subject":
+		value, ok := req.Subject[key]
+		return value, ok
+	case "resource":
+		switch key {
+		case "type":
+			return req.Resource.Type, true
+		case "id":
+			return req.Resource.ID, true
+		}
+		value, ok := req.Resource.Attributes[key]
+		return value, ok
+	case "env", "environment":
+		value, ok := req.Environment[key]
+		return value, ok
+	}
+	return nil, false
+}
+
+func valuesEqual(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func listContains(list, value any) bool {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < v.Len(); i++ {
+		if valuesEqual(v.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareValues(a, b any) (int, bool) {
+	if x, ok := toTime(a); ok {
+		y, ok := toTime(b)
+		if !ok {
+			return 0, false
+		}
+		return x.Compare(y), true
+	}
+
+	x, ok := toFloat(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := toFloat(b)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
+type environmentKey struct{}
+
+// ContextWithEnvironment attaches environment attributes (client IP, device,
+// and so on) that policy conditions can refer to as "env.<name>".
+func ContextWithEnvironment(ctx context.Context, env map[string]any) context.Context {
+	return context.WithValue(ctx, environmentKey{}, env)
+}
+
+func environmentFromContext(ctx context.Context, now time.Time) map[string]any {
+	env := map[string]any{"time": now}
+	if values, ok := ctx.Value(environmentKey{}).(map[string]any); ok {
+		for key, value := range values {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// Authorize evaluates the configured policy for subject performing action on
+// resource. The returned decision carries the rule that decided it.
+func (s *Service) Authorize(ctx context.Context, subject User, action string, resource Resource) (PolicyDecision, error) {
+	if s.policy == nil {
+		return PolicyDecision{}, ErrPolicyNotConfigured
+	}
+
+	req := AccessRequest{
+		Subject:     s.subjectAttributes(ctx, subject),
+		Action:      action,
+		Resource:    resource,
+		Environment: environmentFromContext(ctx, s.now()),
+	}
+	return s.policy.Evaluate(req), nil
+}
+
+func (s *Service) subjectAttributes(ctx context.Context, user User) map[string]any {
+	attributes := map[string]any{}
+	for key, value := range user.Attributes {
+		attributes[key] = value
+	}
+
+	attributes["id"] = user.ID
+	attributes["email"] = user.Email
+	attributes["username"] = user.Username
+	attributes["role_id"] = user.RoleID
+	attributes["role"] = ""
+	attributes["roles"] = []string{}
+	attributes["permissions"] = []string{}
+
+	roleIDs, err := s.effectiveRoleIDs(ctx, &user)
+	if err != nil {
+		return attributes
+	}
+
+	roles := []string{}
+	permissions := map[string]struct{}{}
+	for _, roleID := range roleIDs {
+		tree, err := s.resolveRoleTree(ctx, roleID, map[string]bool{})
+		if err != nil {
+			continue
+		}
+		if roleID == user.RoleID {
+			attributes["role"] = tree.Role.Name
+		}
+		roles = append(roles, tree.Role.Name)
+		tree.collectPermissions(permissions)
+	}
+
+	attributes["roles"] = roles
+	attributes["permissions"] = sortedKeys(permissions)
+	return attributes
+}
@@ -0,0 +1,153 @@
+package users
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicyYAML = `
+rules:
+  - id: edit-own-profile
+    effect: allow
+    actions: ["users:update"]
+    resources: ["user"]
+    conditions:
+      - attribute: resource.id
+        operator: eq
+        value_from: subject.id
+  - id: support-views-region
+    effect: allow
+    actions: ["users:read"]
+    resources: ["user"]
+    conditions:
+      - attribute: subject.role
+        operator: eq
+        value: support
+      - attribute: resource.region
+        operator: eq
+        value_from: subject.region
+  - id: no-blocked-networks
+    effect: deny
+    actions: ["*"]
+    resources: ["*"]
+    conditions:
+      - attribute: env.network
+        operator: in
+        value: ["tor"]
+`
+
+func newPolicyService(t *testing.T) *Service {
+	t.Helper()
+	policy, err := ParsePolicyYAML([]byte(testPolicyYAML))
+	require.NoError(t, err)
+	engine, err := NewPolicyEngine(*policy)
+	require.NoError(t, err)
+
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{"r-support": {ID: "r-support", Name: "support"}}}
+	return NewService(&mockUserRepo{}, roleRepo, &mockHasher{}, &mockTokenizer{}, WithPolicyEngine(engine))
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	svc := newPolicyService(t)
+	alice := User{ID: "alice", Attributes: map[string]string{"region": "eu"}}
+	bob := User{ID: "bob", Attributes: map[string]string{"region": "us"}}
+	support := User{ID: "s1", RoleID: "r-support", Attributes: map[string]string{"region": "eu"}}
+
+	t.Run("user edits own profile", func(t *testing.T) {
+		decision, err := svc.Authorize(ctx, alice, "users:update", UserResource(alice))
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+		require.Equal(t, "edit-own-profile", decision.Rule.ID)
+	})
+
+	t.Run("user edits someone else", func(t *testing.T) {
+		decision, err := svc.Authorize(ctx, alice, "users:update", UserResource(bob))
+		require.NoError(t, err)
+		require.False(t, decision.Allowed)
+		require.Nil(t, decision.Rule)
+	})
+
+	t.Run("support views user in region", func(t *testing.T) {
+		decision, err := svc.Authorize(ctx, support, "users:read", UserResource(alice))
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+		require.Equal(t, "support-views-region", decision.Rule.ID)
+	})
+
+	t.Run("support views user outside region", func(t *testing.T) {
+		decision, err := svc.Authorize(ctx, support, "users:read", UserResource(bob))
+		require.NoError(t, err)
+		require.False(t, decision.Allowed)
+	})
+
+	t.Run("deny overrides allow", func(t *testing.T) {
+		ctx := ContextWithEnvironment(ctx, map[string]any{"network": "tor"})
+		decision, err := svc.Authorize(ctx, alice, "users:update", UserResource(alice))
+		require.NoError(t, err)
+		require.False(t, decision.Allowed)
+		require.Equal(t, "no-blocked-networks", decision.Rule.ID)
+	})
+
+	t.Run("attributes cannot spoof the role", func(t *testing.T) {
+		for _, roleID := range []string{"", "r-deleted"} {
+			spoofer := User{ID: "eve", RoleID: roleID, Attributes: map[string]string{"region": "eu", "role": "support", "roles": "support"}}
+			decision, err := svc.Authorize(ctx, spoofer, "users:read", UserResource(alice))
+			require.NoError(t, err)
+			require.False(t, decision.Allowed, roleID)
+
+			subject := svc.subjectAttributes(ctx, spoofer)
+			require.Equal(t, "", subject["role"])
+			require.Equal(t, []string{}, subject["roles"])
+			require.Equal(t, []string{}, subject["permissions"])
+		}
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		svc := NewService(&mockUserRepo{}, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.Authorize(ctx, alice, "users:update", UserResource(alice))
+		require.ErrorIs(t, err, ErrPolicyNotConfigured)
+	})
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(dir, "policy.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"rules":[{"id":"r","effect":"allow","actions":["users:*"],"resources":["user"],"conditions":[{"attribute":"env.hour","operator":"lt","value":18}]}]}`), 0o600))
+		policy, err := LoadPolicyFile(path)
+		require.NoError(t, err)
+		engine, err := NewPolicyEngine(*policy)
+		require.NoError(t, err)
+
+		decision := engine.Evaluate(AccessRequest{Action: "users:list", Resource: Resource{Type: "user"}, Environment: map[string]any{"hour": 9}})
+		require.True(t, decision.Allowed)
+		decision = engine.Evaluate(AccessRequest{Action: "users:list", Resource: Resource{Type: "user"}, Environment: map[string]any{"hour": 20}})
+		require.False(t, decision.Allowed)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		path := filepath.Join(dir, "policy.yml")
+		require.NoError(t, os.WriteFile(path, []byte(testPolicyYAML), 0o600))
+		policy, err := LoadPolicyFile(path)
+		require.NoError(t, err)
+		require.Len(t, policy.Rules, 3)
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := filepath.Join(dir, "policy.toml")
+		require.NoError(t, os.WriteFile(path, []byte(""), 0o600))
+		_, err := LoadPolicyFile(path)
+		require.ErrorIs(t, err, ErrInvalidPolicy)
+	})
+
+	t.Run("invalid effect", func(t *testing.T) {
+		_, err := NewPolicyEngine(Policy{Rules: []PolicyRule{{Effect: "maybe", Actions: []string{"*"}, Resources: []string{"*"}}}})
+		require.ErrorIs(t, err, ErrInvalidPolicy)
+	})
+}
@@ -0,0 +1,81 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ProblemContentType is the media type to serve a Problem with.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix is joined with an error code to form Problem.Type.
+// Deployments that document their errors on the web can point it at that
+// documentation instead.
+var ProblemTypePrefix = "urn:users-core:error:"
+
+// Problem is an RFC 7807 problem details object. Code, Category, Fields and
+// Suspension are extension members; Fields is only set for a ValidationError
+// and Suspension for an AccountSuspendedError.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Code     string        `json:"code"`
+	Category ErrorCategory `json:"category"`
+	Fields   []FieldError  `json:"fields,omitempty"`
+
+	Suspension *ProblemSuspension `json:"suspension,omitempty"`
+}
+
+// ProblemSuspension tells a suspended or banned user why and for how long.
+type ProblemSuspension struct {
+	Status    UserStatus `json:"status"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewProblem converts a non-nil error returned by Service into a Problem
+// with a suggested HTTP status. A ValidationError becomes a 422 listing the
+// invalid fields; other errors take their status from their category. Detail
+// is left empty for internal and unavailable errors, whose wrapped messages
+// come from storage, drivers or remote services, and is only the sentinel
+// message for forbidden errors, which wrap policy rule and actor IDs, except
+// for an AccountSuspendedError, whose details are the user's own. Log err
+// itself for the full story.
+func NewProblem(err error) Problem {
+	e := asError(err)
+	problem := Problem{
+		Type:     ProblemTypePrefix + e.Code,
+		Title:    e.Message,
+		Status:   e.Category.HTTPStatus(),
+		Code:     e.Code,
+		Category: e.Category,
+	}
+	var suspended *AccountSuspendedError
+	switch {
+	case errors.As(err, &suspended):
+		problem.Detail = suspended.Error()
+		problem.Suspension = &ProblemSuspension{
+			Status:    suspended.Status,
+			Reason:    suspended.Reason,
+			ExpiresAt: suspended.ExpiresAt,
+		}
+	case e.Category == CategoryInternal, e.Category == CategoryUnavailable:
+		// Nothing here is meant for clients.
+	case e.Category == CategoryForbidden:
+		problem.Detail = e.Message
+	default:
+		problem.Detail = err.Error()
+	}
+
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		problem.Status = http.StatusUnprocessableEntity
+		problem.Fields = invalid.Fields
+	}
+	return problem
+}
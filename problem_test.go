@@ -0,0 +1,130 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCodes(t *testing.T) {
+	// Every sentinel in errors.go must be an Error with a unique code.
+	file, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	require.NoError(t, err)
+	codes := map[string]string{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			name := value.Names[0].Name
+			if !ast.IsExported(name) {
+				continue
+			}
+			call, ok := value.Values[0].(*ast.CallExpr)
+			require.True(t, ok && fmt.Sprint(call.Fun) == "newError", "%s must be declared with newError", name)
+			code := call.Args[0].(*ast.BasicLit).Value
+			require.NotContains(t, codes, code, "%s reuses the code of %s", name, codes[code])
+			codes[code] = name
+		}
+	}
+	require.NotEmpty(t, codes)
+
+	require.Equal(t, "user_not_found", ErrorCode(fmt.Errorf("%w: id 7", ErrUserNotFound)))
+	require.Equal(t, CategoryNotFound, ErrorCategoryOf(ErrUserNotFound))
+	require.Equal(t, "validation_failed", ErrorCode(invalidField("email", CodeTaken, ErrEmailTaken, "is already taken")))
+	require.Equal(t, "internal", ErrorCode(errors.New("boom")))
+	require.Equal(t, CategoryInternal, ErrorCategoryOf(errors.New("boom")))
+}
+
+func TestNewProblem(t *testing.T) {
+	t.Run("sentinel", func(t *testing.T) {
+		problem := NewProblem(fmt.Errorf("%w: by another request", ErrConflict))
+		require.Equal(t, Problem{
+			Type:     "urn:users-core:error:conflict",
+			Title:    "record was modified concurrently",
+			Status:   http.StatusConflict,
+			Detail:   "record was modified concurrently: by another request",
+			Code:     "conflict",
+			Category: CategoryConflict,
+		}, problem)
+	})
+
+	t.Run("statuses", func(t *testing.T) {
+		for err, status := range map[error]int{
+			ErrInvalidCursor:         http.StatusBadRequest,
+			ErrInvalidCredentials:    http.StatusUnauthorized,
+			ErrForbidden:             http.StatusForbidden,
+			ErrRoleNotFound:          http.StatusNotFound,
+			ErrEmailTaken:            http.StatusConflict,
+			ErrUserErased:            http.StatusGone,
+			ErrSearchNotConfigured:   http.StatusNotImplemented,
+			ErrWebhookDeliveryFailed: http.StatusBadGateway,
+			ErrFailedToWriteAudit:    http.StatusInternalServerError,
+		} {
+			require.Equal(t, status, NewProblem(err).Status, err.Error())
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		err := invalidField("email", CodeTaken, ErrEmailTaken, "is already taken")
+		problem := NewProblem(err)
+		require.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+		require.Equal(t, "validation_failed", problem.Code)
+		require.Equal(t, err.Fields, problem.Fields)
+
+		data, jsonErr := json.Marshal(problem)
+		require.NoError(t, jsonErr)
+		require.JSONEq(t, `{
+			"type": "urn:users-core:error:validation_failed",
+			"title": "validation failed",
+			"status": 422,
+			"detail": "validation failed: email: is already taken",
+			"code": "validation_failed",
+			"category": "invalid",
+			"fields": [{"field": "email", "code": "taken", "message": "is already taken"}]
+		}`, string(data))
+	})
+
+	t.Run("forbidden and unavailable details are hidden", func(t *testing.T) {
+		problem := NewProblem(fmt.Errorf("%w: users:read denied by rule deny-eu", ErrForbidden))
+		require.Equal(t, "forbidden", problem.Detail)
+
+		problem = NewProblem(fmt.Errorf("%w: smtp: 535 authentication failed", ErrFailedToSendInvitation))
+		require.Equal(t, http.StatusBadGateway, problem.Status)
+		require.Empty(t, problem.Detail)
+	})
+
+	t.Run("suspension details are kept", func(t *testing.T) {
+		until := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		problem := NewProblem(fmt.Errorf("login: %w", &AccountSuspendedError{Status: StatusSuspended, Reason: "spam", ExpiresAt: &until}))
+		require.Equal(t, http.StatusForbidden, problem.Status)
+		require.Equal(t, "account_suspended", problem.Code)
+		require.Equal(t, "account suspended: spam until 2025-02-01T00:00:00Z", problem.Detail)
+		require.Equal(t, &ProblemSuspension{Status: StatusSuspended, Reason: "spam", ExpiresAt: &until}, problem.Suspension)
+
+		data, err := json.Marshal(problem.Suspension)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"status": "suspended", "reason": "spam", "expires_at": "2025-02-01T00:00:00Z"}`, string(data))
+	})
+
+	t.Run("internal details are hidden", func(t *testing.T) {
+		problem := NewProblem(fmt.Errorf("%w: connection refused", ErrFailedToUpdateUser))
+		require.Equal(t, "failed_to_update_user", problem.Code)
+		require.Empty(t, problem.Detail)
+
+		problem = NewProblem(errors.New("driver: bad connection"))
+		require.Equal(t, http.StatusInternalServerError, problem.Status)
+		require.Equal(t, "internal", problem.Code)
+		require.Empty(t, problem.Detail)
+	})
+}
@@ -0,0 +1,127 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const MaxDisplayNameLength = 100
+
+// UpdateUserProfile applies patch to the user's profile. Unlike UpdateUser it
+// never touches fields the patch leaves unset, so callers need not read the
+// user first. A patch that changes nothing succeeds without writing.
+func (s *Service) UpdateUserProfile(ctx context.Context, id string, patch UserPatch) (*User, error) {
+	if err := s.authorizeUser(ctx, PermissionUsersUpdate, id); err != nil {
+		return nil, err
+	}
+
+	patch = patch.normalized()
+	if err := patch.validate(s.usernamePolicy, s.emailPolicy); err != nil {
+		return nil, err
+	}
+
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		user, err := s.activeUser(ctx, id)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		if user.ErasedAt != nil {
+			return nil, ErrUserErased
+		}
+
+		patched := *user
+		changed := patch.applyTo(&patched)
+		if len(changed) == 0 {
+			return user, nil
+		}
+		if patched.Email != user.Email {
+			if _, err := s.checkEmail(ctx, patched.Email, id); err != nil {
+				return nil, err
+			}
+		}
+		if patched.Username != user.Username {
+			if _, err := s.checkUsername(ctx, patched.Username, id); err != nil {
+				return nil, err
+			}
+		}
+
+		updatedUser, err := s.userRepo.Update(ctx, patched)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateUser, err)
+		}
+
+		if err := s.audit(ctx, AuditProfileUpdated, "user", id, user, updatedUser); err != nil {
+			return nil, err
+		}
+		if err := s.publish(ctx, ProfileUpdated{EventMeta: s.eventMeta(ctx, id), ChangedFields: changed}); err != nil {
+			return nil, err
+		}
+		return updatedUser, nil
+	})
+}
+
+func (p UserPatch) normalized() UserPatch {
+	apply := func(value *string, normalize func(string) string) *string {
+		if value == nil {
+			return nil
+		}
+		normalized := normalize(*value)
+		return &normalized
+	}
+	return UserPatch{
+		Email: apply(p.Email, func(email string) string {
+			if normalized, err := NormalizeEmail(email); err == nil {
+				return normalized
+			}
+			// Left for validate to report.
+			return strings.TrimSpace(email)
+		}),
+		Username:    apply(p.Username, NormalizeUsername),
+		DisplayName: apply(p.DisplayName, strings.TrimSpace),
+	}
+}
+
+// validate reports every invalid field at once as a ValidationError.
+func (p UserPatch) validate(usernamePolicy UsernamePolicy, emailPolicy EmailPolicy) error {
+	// The validators only ever fail with a ValidationError, so collect
+	// never hands an error back.
+	invalid := &ValidationError{}
+	if p.Email != nil {
+		email, err := NormalizeEmail(*p.Email)
+		if err == nil {
+			err = emailPolicy.Validate(email)
+		}
+		_ = invalid.collect(err)
+	}
+	if p.Username != nil {
+		_ = invalid.collect(usernamePolicy.Validate(*p.Username))
+	}
+	if p.DisplayName != nil {
+		switch {
+		case utf8.RuneCountInString(*p.DisplayName) > MaxDisplayNameLength:
+			invalid.add("display_name", CodeTooLong, ErrInvalidProfile, fmt.Sprintf("must be at most %d characters", MaxDisplayNameLength))
+		case strings.ContainsFunc(*p.DisplayName, unicode.IsControl):
+			invalid.add("display_name", CodeInvalidCharacter, ErrInvalidProfile, "must not contain control characters")
+		}
+	}
+	return invalid.orNil()
+}
+
+// applyTo sets the patched fields on user and returns the JSON names of those
+// whose value changed.
+func (p UserPatch) applyTo(user *User) []string {
+	var changed []string
+	set := func(name string, field *string, value *string) {
+		if value != nil && *value != *field {
+			*field = *value
+			changed = append(changed, name)
+		}
+	}
+	set("email", &user.Email, p.Email)
+	set("username", &user.Username, p.Username)
+	set("display_name", &user.DisplayName, p.DisplayName)
+	return changed
+}
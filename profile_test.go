@@ -0,0 +1,70 @@
+package users
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestUpdateUserProfile(t *testing.T) {
+	svc, userRepo := newEnforcingService()
+	events := &recordingPublisher{}
+	svc.events = events
+	ctx := actorCtx("alice")
+
+	t.Run("changes only the patched fields", func(t *testing.T) {
+		updated, err := svc.UpdateUserProfile(ctx, "alice", UserPatch{
+			Username:    ptr("  alice  "),
+			DisplayName: ptr("Alice Liddell"),
+			Email:       ptr("alice@example.com"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "alice", updated.Username)
+		require.Equal(t, "Alice Liddell", updated.DisplayName)
+		require.Equal(t, "hashed:alice", updated.HashedPassword)
+		require.Equal(t, "r-user", updated.RoleID)
+
+		require.Len(t, events.events, 1)
+		event := events.events[0].(ProfileUpdated)
+		require.Equal(t, []string{"username", "display_name"}, event.ChangedFields)
+		require.Equal(t, "alice", event.ActorID)
+	})
+
+	t.Run("no-op patch does not publish", func(t *testing.T) {
+		events.events = nil
+		_, err := svc.UpdateUserProfile(ctx, "alice", UserPatch{DisplayName: ptr("Alice Liddell")})
+		require.NoError(t, err)
+		require.Empty(t, events.events)
+	})
+
+	t.Run("validation reports every field", func(t *testing.T) {
+		_, err := svc.UpdateUserProfile(ctx, "alice", UserPatch{
+			Email:       ptr("not-an-email"),
+			Username:    ptr("has space"),
+			DisplayName: ptr(strings.Repeat("x", MaxDisplayNameLength+1)),
+		})
+		require.ErrorIs(t, err, ErrInvalidProfile)
+		require.ErrorIs(t, err, ErrInvalidEmail)
+		require.ErrorIs(t, err, ErrInvalidUsername)
+		require.ErrorContains(t, err, "display_name: must be at most 100 characters")
+		require.Equal(t, "alice@example.com", userRepo.users["alice"].Email)
+	})
+
+	t.Run("email taken", func(t *testing.T) {
+		_, err := svc.UpdateUserProfile(ctx, "alice", UserPatch{Email: ptr("bob@example.com")})
+		require.ErrorIs(t, err, ErrEmailTaken)
+	})
+
+	t.Run("other users need permission", func(t *testing.T) {
+		_, err := svc.UpdateUserProfile(ctx, "bob", UserPatch{DisplayName: ptr("Bobby")})
+		require.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := svc.UpdateUserProfile(actorCtx("admin"), "ghost", UserPatch{DisplayName: ptr("Ghost")})
+		require.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
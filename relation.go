@@ -0,0 +1,280 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ObjectRef names an object such as "document:readme".
+type ObjectRef struct {
+	Namespace string
+	ID        string
+}
+
+func (o ObjectRef) String() string {
+	return o.Namespace + ":" + o.ID
+}
+
+// SubjectRef is either a concrete subject such as "user:alice" or, when
+// Relation is set, a userset such as "group:eng#member".
+type SubjectRef struct {
+	Namespace string
+	ID        string
+	Relation  string
+}
+
+func (s SubjectRef) String() string {
+	if s.Relation == "" {
+		return s.Namespace + ":" + s.ID
+	}
+	return s.Namespace + ":" + s.ID + "#" + s.Relation
+}
+
+const UserNamespace = "user"
+
+const PermissionRelationsManage = "relations:manage"
+
+// UserSubject refers to a User of this package by ID.
+func UserSubject(userID string) SubjectRef {
+	return SubjectRef{Namespace: UserNamespace, ID: userID}
+}
+
+// RelationTuple states that Subject has Relation to Object, written as
+// "object#relation@subject".
+type RelationTuple struct {
+	Object   ObjectRef
+	Relation string
+	Subject  SubjectRef
+}
+
+func (t RelationTuple) String() string {
+	return t.Object.String() + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+func ParseRelationTuple(s string) (RelationTuple, error) {
+	objectRelation, subject, ok := strings.Cut(s, "@")
+	if !ok {
+		return RelationTuple{}, fmt.Errorf("%w: %q", ErrInvalidRelationTuple, s)
+	}
+	object, relation, ok := strings.Cut(objectRelation, "#")
+	if !ok || relation == "" {
+		return RelationTuple{}, fmt.Errorf("%w: %q", ErrInvalidRelationTuple, s)
+	}
+
+	objectRef, err := parseObjectRef(object)
+	if err != nil {
+		return RelationTuple{}, fmt.Errorf("%w: %q", ErrInvalidRelationTuple, s)
+	}
+	subjectObject, subjectRelation, _ := strings.Cut(subject, "#")
+	subjectRef, err := parseObjectRef(subjectObject)
+	if err != nil {
+		return RelationTuple{}, fmt.Errorf("%w: %q", ErrInvalidRelationTuple, s)
+	}
+
+	return RelationTuple{
+		Object:   objectRef,
+		Relation: relation,
+		Subject:  SubjectRef{Namespace: subjectRef.Namespace, ID: subjectRef.ID, Relation: subjectRelation},
+	}, nil
+}
+
+func parseObjectRef(s string) (ObjectRef, error) {
+	namespace, id, ok := strings.Cut(s, ":")
+	if !ok || namespace == "" || id == "" {
+		return ObjectRef{}, ErrInvalidRelationTuple
+	}
+	return ObjectRef{Namespace: namespace, ID: id}, nil
+}
+
+// NamespaceConfig declares how each relation of a namespace is computed.
+// Relations without a rewrite are satisfied by direct tuples only.
+type NamespaceConfig struct {
+	Name      string
+	Relations map[string]UsersetRewrite
+}
+
+// UsersetRewrite grants a relation to the union of its usersets.
+type UsersetRewrite struct {
+	Union []Userset
+}
+
+type Userset struct {
+	// This includes subjects with a direct tuple for the relation.
+	This bool
+	// ComputedRelation includes subjects holding another relation on the same
+	// object, e.g. viewers include editors.
+	ComputedRelation string
+	// TupleToUserset follows TuplesetRelation to other objects and includes
+	// subjects holding ComputedRelation there, e.g. viewers of a document's
+	// parent folder.
+	TupleToUserset *TupleToUserset
+}
+
+type TupleToUserset struct {
+	TuplesetRelation string
+	ComputedRelation string
+}
+
+const DefaultRelationCheckDepth = 25
+
+type RelationChecker struct {
+	store      RelationTupleStore
+	namespaces map[string]NamespaceConfig
+	maxDepth   int
+}
+
+func NewRelationChecker(store RelationTupleStore, namespaces ...NamespaceConfig) *RelationChecker {
+	c := &RelationChecker{
+		store:      store,
+		namespaces: map[string]NamespaceConfig{},
+		maxDepth:   DefaultRelationCheckDepth,
+	}
+	for _, ns := range namespaces {
+		c.namespaces[ns.Name] = ns
+	}
+	return c
+}
+
+func (c *RelationChecker) Store() RelationTupleStore {
+	return c.store
+}
+
+// Check reports whether subject has relation to object, following the
+// namespace rewrites and nested usersets.
+func (c *RelationChecker) Check(ctx context.Context, object ObjectRef, relation string, subject SubjectRef) (bool, error) {
+	return c.check(ctx, object, relation, subject, map[string]bool{}, 0)
+}
+
+func (c *RelationChecker) check(ctx context.Context, object ObjectRef, relation string, subject SubjectRef, path map[string]bool, depth int) (bool, error) {
+	if depth > c.maxDepth {
+		return false, fmt.Errorf("%w: checking %s#%s", ErrRelationDepthExceeded, object, relation)
+	}
+
+	key := object.String() + "#" + relation
+	if path[key] {
+		return false, nil
+	}
+	path[key] = true
+	defer delete(path, key)
+
+	rewrite, ok := c.namespaces[object.Namespace].Relations[relation]
+	if !ok {
+		rewrite = UsersetRewrite{Union: []Userset{{This: true}}}
+	}
+
+	for _, userset := range rewrite.Union {
+		var (
+			allowed bool
+			err     error
+		)
+		switch {
+		case userset.This:
+			allowed, err = c.checkDirect(ctx, object, relation, subject, path, depth)
+		case userset.ComputedRelation != "":
+			allowed, err = c.check(ctx, object, userset.ComputedRelation, subject, path, depth+1)
+		case userset.TupleToUserset != nil:
+			allowed, err = c.checkTupleToUserset(ctx, object, *userset.TupleToUserset, subject, path, depth)
+		}
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+func (c *RelationChecker) checkDirect(ctx context.Context, object ObjectRef, relation string, subject SubjectRef, path map[string]bool, depth int) (bool, error) {
+	tuples, err := c.store.Read(ctx, object, relation)
+	if err != nil {
+		return false, err
+	}
+
+	for _, tuple := range tuples {
+		if tuple.Subject == subject {
+			return true, nil
+		}
+	}
+	for _, tuple := range tuples {
+		if tuple.Subject.Relation == "" {
+			continue
+		}
+		nested := ObjectRef{Namespace: tuple.Subject.Namespace, ID: tuple.Subject.ID}
+		allowed, err := c.check(ctx, nested, tuple.Subject.Relation, subject, path, depth+1)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+func (c *RelationChecker) checkTupleToUserset(ctx context.Context, object ObjectRef, rewrite TupleToUserset, subject SubjectRef, path map[string]bool, depth int) (bool, error) {
+	tuples, err := c.store.Read(ctx, object, rewrite.TuplesetRelation)
+	if err != nil {
+		return false, err
+	}
+
+	for _, tuple := range tuples {
+		related := ObjectRef{Namespace: tuple.Subject.Namespace, ID: tuple.Subject.ID}
+		allowed, err := c.check(ctx, related, rewrite.ComputedRelation, subject, path, depth+1)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+func WithRelationChecker(checker *RelationChecker) ServiceOption {
+	return func(s *Service) {
+		s.relations = checker
+	}
+}
+
+// CheckRelation reports whether the user has relation to object. Like
+// EffectiveRoles it needs users:read on the user.
+func (s *Service) CheckRelation(ctx context.Context, userID string, object ObjectRef, relation string) (bool, error) {
+	if s.relations == nil {
+		return false, ErrRelationsNotConfigured
+	}
+	if err := s.authorizeUser(ctx, PermissionUsersRead, userID); err != nil {
+		return false, err
+	}
+	if _, err := s.activeUser(ctx, userID); err != nil {
+		return false, ErrUserNotFound
+	}
+	return s.relations.Check(ctx, object, relation, UserSubject(userID))
+}
+
+func (s *Service) GrantRelation(ctx context.Context, userID string, object ObjectRef, relation string) error {
+	tuple, err := s.userRelationTuple(ctx, userID, object, relation)
+	if err != nil {
+		return err
+	}
+	if err := s.relations.store.Write(ctx, tuple); err != nil {
+		return err
+	}
+	return s.audit(ctx, AuditRelationGranted, "user", userID, nil, tuple)
+}
+
+func (s *Service) RevokeRelation(ctx context.Context, userID string, object ObjectRef, relation string) error {
+	tuple, err := s.userRelationTuple(ctx, userID, object, relation)
+	if err != nil {
+		return err
+	}
+	if err := s.relations.store.Delete(ctx, tuple); err != nil {
+		return err
+	}
+	return s.audit(ctx, AuditRelationRevoked, "user", userID, tuple, nil)
+}
+
+func (s *Service) userRelationTuple(ctx context.Context, userID string, object ObjectRef, relation string) (RelationTuple, error) {
+	if s.relations == nil {
+		return RelationTuple{}, ErrRelationsNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionRelationsManage, Resource{Type: object.Namespace, ID: object.ID}); err != nil {
+		return RelationTuple{}, err
+	}
+	if _, err := s.activeUser(ctx, userID); err != nil {
+		return RelationTuple{}, ErrUserNotFound
+	}
+	return RelationTuple{Object: object, Relation: relation, Subject: UserSubject(userID)}, nil
+}
@@ -0,0 +1,77 @@
+package users
+
+import (
+	"context"
+	"sync"
+)
+
+type RelationTupleStore interface {
+	Write(ctx context.Context, tuples ...RelationTuple) error
+	Delete(ctx context.Context, tuples ...RelationTuple) error
+	// Read returns the tuples stored for object#relation.
+	Read(ctx context.Context, object ObjectRef, relation string) ([]RelationTuple, error)
+}
+
+// MemoryRelationTupleStore is a RelationTupleStore kept in process memory.
+type MemoryRelationTupleStore struct {
+	mu     sync.RWMutex
+	tuples map[string][]RelationTuple
+}
+
+func NewMemoryRelationTupleStore() *MemoryRelationTupleStore {
+	return &MemoryRelationTupleStore{tuples: map[string][]RelationTuple{}}
+}
+
+func relationKey(object ObjectRef, relation string) string {
+	return object.String() + "#" + relation
+}
+
+func (m *MemoryRelationTupleStore) Write(ctx context.Context, tuples ...RelationTuple) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tuple := range tuples {
+		key := relationKey(tuple.Object, tuple.Relation)
+		if !containsTuple(m.tuples[key], tuple) {
+			m.tuples[key] = append(m.tuples[key], tuple)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRelationTupleStore) Delete(ctx context.Context, tuples ...RelationTuple) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tuple := range tuples {
+		key := relationKey(tuple.Object, tuple.Relation)
+		kept := m.tuples[key][:0]
+		for _, existing := range m.tuples[key] {
+			if existing != tuple {
+				kept = append(kept, existing)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.tuples, key)
+		} else {
+			m.tuples[key] = kept
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRelationTupleStore) Read(ctx context.Context, object ObjectRef, relation string) ([]RelationTuple, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]RelationTuple(nil), m.tuples[relationKey(object, relation)]...), nil
+}
+
+func containsTuple(tuples []RelationTuple, tuple RelationTuple) bool {
+	for _, existing := range tuples {
+		if existing == tuple {
+			return true
+		}
+	}
+	return false
+}
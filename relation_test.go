@@ -0,0 +1,139 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var documentNamespace = NamespaceConfig{
+	Name: "document",
+	Relations: map[string]UsersetRewrite{
+		"owner":  {Union: []Userset{{This: true}}},
+		"editor": {Union: []Userset{{This: true}, {ComputedRelation: "owner"}}},
+		"viewer": {Union: []Userset{
+			{This: true},
+			{ComputedRelation: "editor"},
+			{TupleToUserset: &TupleToUserset{TuplesetRelation: "parent", ComputedRelation: "viewer"}},
+		}},
+	},
+}
+
+func mustWriteTuples(t *testing.T, store RelationTupleStore, tuples ...string) {
+	t.Helper()
+	for _, s := range tuples {
+		tuple, err := ParseRelationTuple(s)
+		require.NoError(t, err)
+		require.NoError(t, store.Write(context.Background(), tuple))
+	}
+}
+
+func TestParseRelationTuple(t *testing.T) {
+	t.Run("user subject", func(t *testing.T) {
+		tuple, err := ParseRelationTuple("document:readme#editor@user:alice")
+		require.NoError(t, err)
+		require.Equal(t, RelationTuple{
+			Object:   ObjectRef{Namespace: "document", ID: "readme"},
+			Relation: "editor",
+			Subject:  UserSubject("alice"),
+		}, tuple)
+		require.Equal(t, "document:readme#editor@user:alice", tuple.String())
+	})
+
+	t.Run("userset subject", func(t *testing.T) {
+		tuple, err := ParseRelationTuple("document:readme#viewer@group:eng#member")
+		require.NoError(t, err)
+		require.Equal(t, SubjectRef{Namespace: "group", ID: "eng", Relation: "member"}, tuple.Subject)
+		require.Equal(t, "document:readme#viewer@group:eng#member", tuple.String())
+	})
+
+	for _, invalid := range []string{"", "document:readme", "document:readme#@user:alice", "readme#editor@user:alice", "document:readme#editor@alice"} {
+		_, err := ParseRelationTuple(invalid)
+		require.ErrorIs(t, err, ErrInvalidRelationTuple, invalid)
+	}
+}
+
+func TestRelationChecker(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRelationTupleStore()
+	checker := NewRelationChecker(store, documentNamespace)
+	mustWriteTuples(t, store,
+		"document:readme#owner@user:olivia",
+		"document:readme#editor@user:alice",
+		"document:readme#viewer@group:eng#member",
+		"group:eng#member@user:bob",
+		"document:readme#parent@folder:docs",
+		"folder:docs#viewer@user:carol",
+	)
+	readme := ObjectRef{Namespace: "document", ID: "readme"}
+
+	cases := []struct {
+		relation string
+		user     string
+		allowed  bool
+	}{
+		{"editor", "alice", true},
+		{"viewer", "alice", true},
+		{"owner", "alice", false},
+		{"editor", "olivia", true},
+		{"viewer", "olivia", true},
+		{"viewer", "bob", true},
+		{"editor", "bob", false},
+		{"viewer", "carol", true},
+		{"editor", "carol", false},
+		{"viewer", "mallory", false},
+	}
+	for _, tc := range cases {
+		allowed, err := checker.Check(ctx, readme, tc.relation, UserSubject(tc.user))
+		require.NoError(t, err)
+		require.Equal(t, tc.allowed, allowed, "%s %s", tc.user, tc.relation)
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		tuple, err := ParseRelationTuple("group:eng#member@user:bob")
+		require.NoError(t, err)
+		require.NoError(t, store.Delete(ctx, tuple))
+		allowed, err := checker.Check(ctx, readme, "viewer", UserSubject("bob"))
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("cyclic usersets terminate", func(t *testing.T) {
+		mustWriteTuples(t, store, "group:a#member@group:b#member", "group:b#member@group:a#member")
+		allowed, err := checker.Check(ctx, ObjectRef{Namespace: "group", ID: "a"}, "member", UserSubject("alice"))
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+}
+
+func TestServiceRelations(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &mockUserRepo{users: map[string]*User{"alice": {ID: "alice"}}}
+	checker := NewRelationChecker(NewMemoryRelationTupleStore(), documentNamespace)
+	svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{}, WithRelationChecker(checker))
+	readme := ObjectRef{Namespace: "document", ID: "readme"}
+
+	require.NoError(t, svc.GrantRelation(ctx, "alice", readme, "editor"))
+	allowed, err := svc.CheckRelation(ctx, "alice", readme, "viewer")
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	require.NoError(t, svc.RevokeRelation(ctx, "alice", readme, "editor"))
+	allowed, err = svc.CheckRelation(ctx, "alice", readme, "viewer")
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	require.ErrorIs(t, svc.GrantRelation(ctx, "ghost", readme, "editor"), ErrUserNotFound)
+
+	userRepo.users["bob"] = &User{ID: "bob"}
+	svc.enforceAuthz = true
+	_, err = svc.CheckRelation(actorCtx("bob"), "alice", readme, "viewer")
+	require.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.CheckRelation(actorCtx("alice"), "alice", readme, "viewer")
+	require.NoError(t, err, "users may check their own relations")
+
+	svc = NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+	_, err = svc.CheckRelation(ctx, "alice", readme, "viewer")
+	require.ErrorIs(t, err, ErrRelationsNotConfigured)
+}
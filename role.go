@@ -1,11 +1,15 @@
 package users
 
 type Role struct {
-	ID   string
-	Name string
+	ID          string
+	Name        string
+	ParentIDs   []string
+	Permissions []string
+	Version     int64
 }
 
 const (
-	RoleAdmin = "admin"
-	RoleUser  = "user"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleUser      = "user"
 )
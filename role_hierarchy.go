@@ -0,0 +1,134 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RoleTree is a role together with the resolved trees of the roles it
+// inherits from.
+type RoleTree struct {
+	Role    Role
+	Parents []*RoleTree
+}
+
+// EffectivePermissions returns the sorted union of the permissions granted by
+// the role and every role it inherits from.
+func (t *RoleTree) EffectivePermissions() []string {
+	set := map[string]struct{}{}
+	t.collectPermissions(set)
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (t *RoleTree) collectPermissions(set map[string]struct{}) {
+	for _, permission := range t.Role.Permissions {
+		set[permission] = struct{}{}
+	}
+	for _, parent := range t.Parents {
+		parent.collectPermissions(set)
+	}
+}
+
+// String renders the tree with one role per line, each followed by the
+// permissions it grants directly.
+func (t *RoleTree) String() string {
+	var b strings.Builder
+	t.write(&b, "", "")
+	return b.String()
+}
+
+func (t *RoleTree) write(b *strings.Builder, prefix, childPrefix string) {
+	b.WriteString(prefix)
+	b.WriteString(t.Role.Name)
+	if len(t.Role.Permissions) > 0 {
+		b.WriteString(" [")
+		b.WriteString(strings.Join(t.Role.Permissions, ", "))
+		b.WriteString("]")
+	}
+	b.WriteString("\n")
+
+	for i, parent := range t.Parents {
+		if i == len(t.Parents)-1 {
+			parent.write(b, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			parent.write(b, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+func (s *Service) PermissionTree(ctx context.Context, roleID string) (*RoleTree, error) {
+	if err := s.authorizeRole(ctx, PermissionRolesRead, roleID); err != nil {
+		return nil, err
+	}
+	return s.resolveRoleTree(ctx, roleID, map[string]bool{})
+}
+
+func (s *Service) EffectivePermissions(ctx context.Context, roleID string) ([]string, error) {
+	tree, err := s.PermissionTree(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return tree.EffectivePermissions(), nil
+}
+
+func (s *Service) resolveRoleTree(ctx context.Context, roleID string, path map[string]bool) (*RoleTree, error) {
+	if path[roleID] {
+		return nil, fmt.Errorf("%w: role %s", ErrRoleCycle, roleID)
+	}
+
+	role, err := s.roleRepo.GetByID(ctx, roleID)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	path[roleID] = true
+	defer delete(path, roleID)
+
+	tree := &RoleTree{Role: *role}
+	for _, parentID := range role.ParentIDs {
+		parent, err := s.resolveRoleTree(ctx, parentID, path)
+		if err != nil {
+			return nil, err
+		}
+		tree.Parents = append(tree.Parents, parent)
+	}
+	return tree, nil
+}
+
+// validateRoleParents makes sure every parent of role exists and that none of
+// them inherits, directly or transitively, from role itself.
+func (s *Service) validateRoleParents(ctx context.Context, role Role) error {
+	visited := map[string]bool{}
+	pending := append([]string(nil), role.ParentIDs...)
+
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if role.ID != "" && id == role.ID {
+			return fmt.Errorf("%w: %s would inherit from itself", ErrRoleCycle, role.Name)
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		parent, err := s.roleRepo.GetByID(ctx, id)
+		if err != nil {
+			return ErrRoleNotFound
+		}
+		pending = append(pending, parent.ParentIDs...)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newHierarchyRoleRepo() *mockRoleRepo {
+	return &mockRoleRepo{roles: map[string]*Role{
+		"user":      {ID: "user", Name: RoleUser, Permissions: []string{"profile:read"}},
+		"moderator": {ID: "moderator", Name: RoleModerator, ParentIDs: []string{"user"}, Permissions: []string{"users:suspend"}},
+		"admin":     {ID: "admin", Name: RoleAdmin, ParentIDs: []string{"moderator"}, Permissions: []string{"users:delete"}},
+	}}
+}
+
+func TestEffectivePermissions(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(&mockUserRepo{}, newHierarchyRoleRepo(), &mockHasher{}, &mockTokenizer{})
+
+	t.Run("inherits from ancestors", func(t *testing.T) {
+		perms, err := svc.EffectivePermissions(ctx, "admin")
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile:read", "users:delete", "users:suspend"}, perms)
+	})
+
+	t.Run("leaf role", func(t *testing.T) {
+		perms, err := svc.EffectivePermissions(ctx, "user")
+		require.NoError(t, err)
+		require.Equal(t, []string{"profile:read"}, perms)
+	})
+
+	t.Run("role not found", func(t *testing.T) {
+		_, err := svc.EffectivePermissions(ctx, "notfound")
+		require.ErrorIs(t, err, ErrRoleNotFound)
+	})
+}
+
+func TestPermissionTree(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := newHierarchyRoleRepo()
+	roleRepo.roles["support"] = &Role{ID: "support", Name: "support", Permissions: []string{"users:read"}}
+	roleRepo.roles["moderator"].ParentIDs = []string{"user", "support"}
+	svc := NewService(&mockUserRepo{}, roleRepo, &mockHasher{}, &mockTokenizer{})
+
+	tree, err := svc.PermissionTree(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, ""+
+		"admin [users:delete]\n"+
+		"└── moderator [users:suspend]\n"+
+		"    ├── user [profile:read]\n"+
+		"    └── support [users:read]\n", tree.String())
+}
+
+func TestRoleHierarchyCycles(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := newHierarchyRoleRepo()
+	svc := NewService(&mockUserRepo{}, roleRepo, &mockHasher{}, &mockTokenizer{})
+
+	t.Run("create with self as parent", func(t *testing.T) {
+		_, err := svc.CreateRole(ctx, Role{ID: "r", Name: "r", ParentIDs: []string{"r"}})
+		require.ErrorIs(t, err, ErrRoleCycle)
+	})
+
+	t.Run("create with missing parent", func(t *testing.T) {
+		_, err := svc.CreateRole(ctx, Role{ID: "r", Name: "r", ParentIDs: []string{"missing"}})
+		require.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("create with valid parent", func(t *testing.T) {
+		r, err := svc.CreateRole(ctx, Role{ID: "owner", Name: "owner", ParentIDs: []string{"admin"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"admin"}, r.ParentIDs)
+	})
+
+	t.Run("update introducing transitive cycle", func(t *testing.T) {
+		user := *roleRepo.roles["user"]
+		user.ParentIDs = []string{"admin"}
+		_, err := svc.UpdateRole(ctx, user)
+		require.ErrorIs(t, err, ErrRoleCycle)
+		require.Empty(t, roleRepo.roles["user"].ParentIDs)
+	})
+
+	t.Run("update role not found", func(t *testing.T) {
+		_, err := svc.UpdateRole(ctx, Role{ID: "notfound"})
+		require.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("resolving corrupted hierarchy", func(t *testing.T) {
+		roleRepo.roles["user"].ParentIDs = []string{"admin"}
+		defer func() { roleRepo.roles["user"].ParentIDs = nil }()
+		_, err := svc.PermissionTree(ctx, "admin")
+		require.ErrorIs(t, err, ErrRoleCycle)
+	})
+}
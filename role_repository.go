@@ -1,7 +1,16 @@
 package users
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
 
+// RoleRepository stores roles with the same versioning contract as
+// UserRepository: Create stores version 1 and Update fails with ErrConflict
+// on a stale Version.
 type RoleRepository interface {
 	Create(ctx context.Context, role Role) (*Role, error)
 	Update(ctx context.Context, role Role) (*Role, error)
@@ -10,3 +19,115 @@ type RoleRepository interface {
 	GetByName(ctx context.Context, name string) (*Role, error)
 	List(ctx context.Context) ([]Role, error)
 }
+
+// MemoryRoleRepository is a RoleRepository kept in process memory.
+type MemoryRoleRepository struct {
+	mu    sync.RWMutex
+	roles map[string]Role
+	seq   int
+}
+
+func NewMemoryRoleRepository() *MemoryRoleRepository {
+	return &MemoryRoleRepository{roles: map[string]Role{}}
+}
+
+func (m *MemoryRoleRepository) Create(ctx context.Context, role Role) (*Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if role.ID == "" {
+		m.seq++
+		role.ID = "role-" + strconv.Itoa(m.seq)
+	}
+	if _, exists := m.roles[role.ID]; exists {
+		return nil, fmt.Errorf("role %s already exists", role.ID)
+	}
+	role.Version = 1
+	m.roles[role.ID] = cloneRole(role)
+	onRollback(ctx, m.restore(role.ID, nil))
+	return &role, nil
+}
+
+func (m *MemoryRoleRepository) Update(ctx context.Context, role Role) (*Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, exists := m.roles[role.ID]
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+	if role.Version != stored.Version {
+		return nil, ErrConflict
+	}
+	role.Version++
+	m.roles[role.ID] = cloneRole(role)
+	onRollback(ctx, m.restore(role.ID, &stored))
+	return &role, nil
+}
+
+func (m *MemoryRoleRepository) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, exists := m.roles[id]; exists {
+		delete(m.roles, id)
+		onRollback(ctx, m.restore(id, &stored))
+	}
+	return nil
+}
+
+func (m *MemoryRoleRepository) GetByID(ctx context.Context, id string) (*Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	role, ok := m.roles[id]
+	if !ok {
+		return nil, ErrRoleNotFound
+	}
+	role = cloneRole(role)
+	return &role, nil
+}
+
+func (m *MemoryRoleRepository) GetByName(ctx context.Context, name string) (*Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, role := range m.roles {
+		if role.Name == name {
+			role = cloneRole(role)
+			return &role, nil
+		}
+	}
+	return nil, ErrRoleNotFound
+}
+
+func (m *MemoryRoleRepository) List(ctx context.Context) ([]Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	roles := make([]Role, 0, len(m.roles))
+	for _, role := range m.roles {
+		roles = append(roles, cloneRole(role))
+	}
+	return roles, nil
+}
+
+// restore returns a rollback step putting back previous, or removing the role
+// when previous is nil.
+func (m *MemoryRoleRepository) restore(id string, previous *Role) func() {
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if previous == nil {
+			delete(m.roles, id)
+		} else {
+			m.roles[id] = *previous
+		}
+	}
+}
+
+func cloneRole(role Role) Role {
+	role.ParentIDs = slices.Clone(role.ParentIDs)
+	role.Permissions = slices.Clone(role.Permissions)
+	return role
+}
@@ -0,0 +1,278 @@
+package users
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const DefaultSearchLimit = 20
+
+// DefaultMinSimilarity is the share of a query word's trigrams that must
+// occur in an indexed term for MemoryUserSearchIndex to count it as a match.
+// It admits prefixes and single-letter typos in typical names.
+const DefaultMinSimilarity = 0.5
+
+type SearchHit struct {
+	UserID string
+	Score  float64
+}
+
+// UserSearchIndex finds users by partial or misspelled email, username and
+// display name. Service keeps it up to date as users change.
+type UserSearchIndex interface {
+	Index(ctx context.Context, user User) error
+	Remove(ctx context.Context, userID string) error
+	// Search returns at most limit hits, best first.
+	Search(ctx context.Context, query string, limit int) ([]SearchHit, error)
+}
+
+func WithUserSearchIndex(index UserSearchIndex) ServiceOption {
+	return func(s *Service) {
+		s.searchIndex = index
+	}
+}
+
+// SearchUsers returns the users best matching query, most relevant first.
+func (s *Service) SearchUsers(ctx context.Context, query string) ([]User, error) {
+	if s.searchIndex == nil {
+		return nil, ErrSearchNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionUsersList, Resource{Type: "user"}); err != nil {
+		return nil, err
+	}
+
+	hits, err := s.searchIndex.Search(ctx, query, DefaultSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]User, 0, len(hits))
+	for _, hit := range hits {
+		// The index may briefly lag behind the repository.
+		user, err := s.activeUser(ctx, hit.UserID)
+		if err != nil {
+			continue
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+// ReindexUsers rebuilds the search index from the repository and returns the
+// number of users indexed.
+func (s *Service) ReindexUsers(ctx context.Context) (int, error) {
+	if s.searchIndex == nil {
+		return 0, ErrSearchNotConfigured
+	}
+	if err := s.authorize(ctx, PermissionUsersList, Resource{Type: "user"}); err != nil {
+		return 0, err
+	}
+
+	users, err := s.userRepo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	indexed := 0
+	for _, user := range users {
+		if user.DeletedAt != nil || user.ErasedAt != nil {
+			if err := s.searchIndex.Remove(ctx, user.ID); err != nil {
+				return indexed, err
+			}
+			continue
+		}
+		if err := s.searchIndex.Index(ctx, user); err != nil {
+			return indexed, err
+		}
+		indexed++
+	}
+	return indexed, nil
+}
+
+// syncSearchIndex applies the effect of event to the search index. The index
+// is derived data that ReindexUsers can rebuild, so failures are ignored
+// rather than failing a mutation that has already been stored.
+func (s *Service) syncSearchIndex(ctx context.Context, event Event) {
+	if s.searchIndex == nil {
+		return
+	}
+
+	switch event.(type) {
+	case UserDeleted, UserPurged, UserErased:
+		_ = s.searchIndex.Remove(ctx, event.AggregateID())
+	case UserRegistered, UserUpdated, ProfileUpdated, UserRestored:
+		user, err := s.userRepo.GetByID(ctx, event.AggregateID())
+		if err != nil {
+			return
+		}
+		_ = s.searchIndex.Index(ctx, *user)
+	}
+}
+
+// MemoryUserSearchIndex is a trigram index kept in process memory. Terms are
+// padded like PostgreSQL's pg_trgm, with two leading spaces and one trailing,
+// while query words only get the leading padding so that prefixes match
+// fully.
+type MemoryUserSearchIndex struct {
+	mu            sync.RWMutex
+	minSimilarity float64
+	terms         map[string][]string
+	grams         map[string]map[string]bool
+}
+
+func NewMemoryUserSearchIndex() *MemoryUserSearchIndex {
+	return &MemoryUserSearchIndex{
+		minSimilarity: DefaultMinSimilarity,
+		terms:         map[string][]string{},
+		grams:         map[string]map[string]bool{},
+	}
+}
+
+func (m *MemoryUserSearchIndex) Index(ctx context.Context, user User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(user.ID)
+	terms := userSearchTerms(user)
+	m.terms[user.ID] = terms
+	for _, term := range terms {
+		for _, gram := range trigrams("  " + term + " ") {
+			if m.grams[gram] == nil {
+				m.grams[gram] = map[string]bool{}
+			}
+			m.grams[gram][user.ID] = true
+		}
+	}
+	return nil
+}
+
+func (m *MemoryUserSearchIndex) Remove(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(userID)
+	return nil
+}
+
+func (m *MemoryUserSearchIndex) remove(userID string) {
+	for _, term := range m.terms[userID] {
+		for _, gram := range trigrams("  " + term + " ") {
+			delete(m.grams[gram], userID)
+			if len(m.grams[gram]) == 0 {
+				delete(m.grams, gram)
+			}
+		}
+	}
+	delete(m.terms, userID)
+}
+
+// Search matches every query word against the user's terms. A user's score is
+// the mean of each word's best similarity, and users missing any word are
+// left out.
+func (m *MemoryUserSearchIndex) Search(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return []SearchHit{}, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := map[string]bool{}
+	for _, word := range words {
+		for _, gram := range trigrams("  " + word) {
+			for userID := range m.grams[gram] {
+				candidates[userID] = true
+			}
+		}
+	}
+
+	hits := []SearchHit{}
+	for userID := range candidates {
+		total := 0.0
+		matchedAll := true
+		for _, word := range words {
+			best := 0.0
+			for _, term := range m.terms[userID] {
+				best = max(best, termSimilarity(word, term))
+			}
+			if best < m.minSimilarity {
+				matchedAll = false
+				break
+			}
+			total += best
+		}
+		if matchedAll {
+			hits = append(hits, SearchHit{UserID: userID, Score: total / float64(len(words))})
+		}
+	}
+
+	slices.SortFunc(hits, func(a, b SearchHit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// termSimilarity is the share of the word's trigrams found in term, with a
+// small bonus for an exact match so it outranks longer terms it prefixes.
+func termSimilarity(word, term string) float64 {
+	if word == term {
+		return 1.1
+	}
+	wordGrams := trigrams("  " + word)
+	termGrams := map[string]bool{}
+	for _, gram := range trigrams("  " + term + " ") {
+		termGrams[gram] = true
+	}
+	shared := 0
+	for _, gram := range wordGrams {
+		if termGrams[gram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordGrams))
+}
+
+// userSearchTerms returns the lowercased email, username and display name,
+// together with the alphanumeric parts of each.
+func userSearchTerms(user User) []string {
+	var terms []string
+	seen := map[string]bool{}
+	add := func(term string) {
+		if term != "" && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for _, field := range []string{user.Email, user.Username, user.DisplayName} {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !strings.Contains(field, " ") {
+			add(field)
+		}
+		for _, part := range strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			add(part)
+		}
+	}
+	return terms
+}
+
+func trigrams(s string) []string {
+	runes := []rune(s)
+	grams := make([]string, 0, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+3]))
+	}
+	return grams
+}
@@ -0,0 +1,102 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUserSearchIndex(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryUserSearchIndex()
+	for _, user := range []User{
+		{ID: "u1", Email: "john.smith@acme.com", Username: "jsmith", DisplayName: "John Smith"},
+		{ID: "u2", Email: "joan@example.com", Username: "joan", DisplayName: "Joan Jett"},
+		{ID: "u3", Email: "mary@acme.com", Username: "mary_k", DisplayName: "Mary Kay"},
+	} {
+		require.NoError(t, index.Index(ctx, user))
+	}
+
+	search := func(query string) []string {
+		hits, err := index.Search(ctx, query, 10)
+		require.NoError(t, err)
+		var ids []string
+		for _, hit := range hits {
+			ids = append(ids, hit.UserID)
+		}
+		return ids
+	}
+
+	require.Equal(t, []string{"u1"}, search("smi"), "prefix of a name")
+	require.Equal(t, []string{"u1", "u3"}, search("acme"), "email domain")
+	require.Equal(t, []string{"u3"}, search("MARY@AC"), "email prefix, case-insensitive")
+	require.Equal(t, []string{"u1"}, search("jonh smith"), "typo in one of several words")
+	require.Equal(t, []string{"u2", "u1"}, search("joan"), "exact match ranks first")
+	require.Empty(t, search("zzz"))
+	require.Empty(t, search("   "))
+
+	hits, err := index.Search(ctx, "j", 1)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+
+	t.Run("reindex replaces old terms", func(t *testing.T) {
+		require.NoError(t, index.Index(ctx, User{ID: "u3", Email: "mary@newco.com", Username: "mary_k"}))
+		require.Equal(t, []string{"u1"}, search("acme"))
+		require.Equal(t, []string{"u3"}, search("newco"))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		require.NoError(t, index.Remove(ctx, "u1"))
+		require.Empty(t, search("smith"))
+	})
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctx := context.Background()
+	userRepo := NewMemoryUserRepository()
+	roleRepo := &mockRoleRepo{roles: map[string]*Role{"r-user": {ID: "r-user", Name: RoleUser}}}
+	svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithUserSearchIndex(NewMemoryUserSearchIndex()))
+
+	user, err := svc.Register(ctx, UserRegisterInput{Email: "grace@navy.mil", Username: "ghopper", DisplayName: "Grace Hopper", Password: "pw"})
+	require.NoError(t, err)
+
+	found, err := svc.SearchUsers(ctx, "hopp")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, user.ID, found[0].ID)
+
+	user.DisplayName = "Admiral Hopper"
+	_, err = svc.UpdateUser(ctx, *user)
+	require.NoError(t, err)
+	found, err = svc.SearchUsers(ctx, "admiral")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	require.NoError(t, svc.DeleteUser(ctx, user.ID))
+	found, err = svc.SearchUsers(ctx, "grace")
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	_, err = svc.RestoreUser(ctx, user.ID)
+	require.NoError(t, err)
+	found, err = svc.SearchUsers(ctx, "grace")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	t.Run("reindex", func(t *testing.T) {
+		svc.searchIndex = NewMemoryUserSearchIndex()
+		n, err := svc.ReindexUsers(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		found, err := svc.SearchUsers(ctx, "ghopper")
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.SearchUsers(ctx, "grace")
+		require.ErrorIs(t, err, ErrSearchNotConfigured)
+	})
+}
@@ -1,5 +1,7 @@
 package users
 
+import "context"
+
 type PasswordHasher interface {
 	Hash(password string) (string, error)
 	Verify(hashedPassword, password string) bool
@@ -9,3 +11,11 @@ type Tokenizer interface {
 	GenerateToken(email, userID string) (string, error)
 	ValidateToken(token string) (string, error)
 }
+
+type SessionRevoker interface {
+	RevokeUserSessions(ctx context.Context, userID string) error
+}
+
+type Notifier interface {
+	SendInvitation(ctx context.Context, invitation Invitation, token string) error
+}
@@ -2,6 +2,7 @@ package users
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -11,71 +12,172 @@ type Service struct {
 	roleRepo  RoleRepository
 	hasher    PasswordHasher
 	tokenizer Tokenizer
+	policy    *PolicyEngine
+	orgRepo   OrganizationRepository
+	groupRepo GroupRepository
+	relations *RelationChecker
+	exporters []UserDataExporter
+	sessions  SessionRevoker
+	events    EventPublisher
+	outbox    OutboxRepository
+	auditLog  AuditLog
+	now       func() time.Time
+
+	enforceAuthz bool
+
+	invitationRepo   InvitationRepository
+	notifier         Notifier
+	invitationSecret []byte
+	invitationTTL    time.Duration
+
+	webhookRepo WebhookRepository
+	txManager   TxManager
+	searchIndex UserSearchIndex
+
+	usernamePolicy UsernamePolicy
+	emailPolicy    EmailPolicy
 }
 
-func NewService(userRepo UserRepository, roleRepo RoleRepository, hasher PasswordHasher, tokenizer Tokenizer) *Service {
-	return &Service{
+// ServiceOption configures optional Service dependencies.
+type ServiceOption func(*Service)
+
+// WithClock replaces time.Now as the Service's source of the current time.
+func WithClock(now func() time.Time) ServiceOption {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+func WithPolicyEngine(engine *PolicyEngine) ServiceOption {
+	return func(s *Service) {
+		s.policy = engine
+	}
+}
+
+func NewService(userRepo UserRepository, roleRepo RoleRepository, hasher PasswordHasher, tokenizer Tokenizer, opts ...ServiceOption) *Service {
+	s := &Service{
 		userRepo:  userRepo,
 		roleRepo:  roleRepo,
 		hasher:    hasher,
 		tokenizer: tokenizer,
+		now:       time.Now,
+
+		usernamePolicy: DefaultUsernamePolicy(),
+		emailPolicy:    DefaultEmailPolicy(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Service) Register(ctx context.Context, input UserRegisterInput) (*User, error) {
+	// The validators only ever fail with a ValidationError, so collect
+	// never hands an error back.
+	invalid := &ValidationError{}
+	email, err := s.validateEmail(input.Email)
+	_ = invalid.collect(err)
+	username, err := s.validateUsername(input.Username)
+	_ = invalid.collect(err)
+	if input.Password == "" {
+		invalid.add("password", CodeRequired, ErrInvalidPassword, "is required")
+	}
+	if err := invalid.orNil(); err != nil {
+		return nil, err
+	}
+
 	hashedPassword, err := s.hasher.Hash(input.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	existing, err := s.userRepo.GetByEmail(ctx, input.Email)
-	if err == nil && existing != nil {
-		return nil, ErrEmailTaken
-	}
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		taken := &ValidationError{}
+		if err := taken.collect(s.ensureEmailAvailable(ctx, email, "")); err != nil {
+			return nil, err
+		}
+		if err := taken.collect(s.ensureUsernameAvailable(ctx, username, "")); err != nil {
+			return nil, err
+		}
+		if err := taken.orNil(); err != nil {
+			return nil, err
+		}
 
-	role, err := s.roleRepo.GetByName(ctx, RoleUser)
-	if err != nil {
-		role, err = s.roleRepo.Create(ctx, Role{Name: RoleUser})
+		role, err := s.roleRepo.GetByName(ctx, RoleUser)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrFailedToCreateRole, err)
+			role, err = s.roleRepo.Create(ctx, Role{Name: RoleUser})
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrFailedToCreateRole, err)
+			}
 		}
-	}
 
-	user := User{
-		Email:          input.Email,
-		Username:       input.Username,
-		HashedPassword: hashedPassword,
-		LastSeen:       time.Now(),
-		RoleID:         role.ID,
-	}
+		user := User{
+			Email:          email,
+			Username:       username,
+			DisplayName:    input.DisplayName,
+			HashedPassword: hashedPassword,
+			LastSeen:       s.now(),
+			RoleID:         role.ID,
+			CreatedAt:      s.now(),
+		}
 
-	createdUser, err := s.userRepo.Create(ctx, user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
-	}
+		createdUser, err := s.userRepo.Create(ctx, user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
 
-	return createdUser, nil
+		if err := s.audit(ctx, AuditUserRegistered, "user", createdUser.ID, nil, createdUser); err != nil {
+			return nil, err
+		}
+		err = s.publish(ctx, UserRegistered{
+			EventMeta: s.eventMeta(ctx, createdUser.ID),
+			RoleID:    createdUser.RoleID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return createdUser, nil
+	})
 }
 
 func (s *Service) Login(ctx context.Context, input UserLoginInput) (token string, err error) {
-	user, err := s.userRepo.GetByEmail(ctx, input.Email)
-	if err != nil {
+	email := input.Email
+	if normalized, err := NormalizeEmail(email); err == nil {
+		email = normalized
+	}
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil && email != input.Email {
+		// Accounts created before addresses were normalized may still hold
+		// the address as it was typed.
+		user, err = s.userRepo.GetByEmail(ctx, input.Email)
+	}
+	if err != nil || user.DeletedAt != nil || user.ErasedAt != nil {
 		return "", ErrUserNotFound
 	}
 	if !s.hasher.Verify(user.HashedPassword, input.Password) {
 		return "", ErrInvalidCredentials
 	}
+	if err := s.checkAccountStatus(ctx, user); err != nil {
+		return "", err
+	}
 
 	token, err = s.tokenizer.GenerateToken(user.Email, user.ID)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)
 	}
 
+	if err := s.publish(ctx, UserLoggedIn{EventMeta: s.eventMeta(ctx, user.ID)}); err != nil {
+		return "", err
+	}
 	return token, nil
 }
 
 func (s *Service) GetUserByID(ctx context.Context, id string) (*User, error) {
-	user, err := s.userRepo.GetByID(ctx, id)
+	if err := s.authorizeUser(ctx, PermissionUsersRead, id); err != nil {
+		return nil, err
+	}
+
+	user, err := s.activeUser(ctx, id)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -83,30 +185,173 @@ func (s *Service) GetUserByID(ctx context.Context, id string) (*User, error) {
 }
 
 func (s *Service) UpdateUser(ctx context.Context, user User) (*User, error) {
-	updatedUser, err := s.userRepo.Update(ctx, user)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrFailedToUpdateUser, err)
+	if err := s.authorizeUser(ctx, PermissionUsersUpdate, user.ID); err != nil {
+		return nil, err
 	}
-	return updatedUser, nil
+	if s.enforceAuthz {
+		if err := s.authorizeSensitiveChanges(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		existing, err := s.userRepo.GetByID(ctx, user.ID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		if user.Version != existing.Version {
+			return nil, ErrConflict
+		}
+		// Account status only changes through SuspendUser, BanUser and
+		// ReinstateUser, which require users:suspend.
+		user.Status, user.StatusReason = existing.Status, existing.StatusReason
+		user.StatusActorID, user.StatusExpiresAt = existing.StatusActorID, existing.StatusExpiresAt
+		// Likewise DeletedAt belongs to DeleteUser and RestoreUser, which
+		// require users:delete, and ErasedAt to EraseUser.
+		user.DeletedAt, user.ErasedAt = existing.DeletedAt, existing.ErasedAt
+		if user.Email != existing.Email {
+			if user.Email, err = s.checkEmail(ctx, user.Email, user.ID); err != nil {
+				return nil, err
+			}
+		}
+		if user.Username != existing.Username {
+			if user.Username, err = s.checkUsername(ctx, user.Username, user.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		before := *existing
+		updatedUser, err := s.userRepo.Update(ctx, user)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateUser, err)
+		}
+
+		if err := s.audit(ctx, AuditUserUpdated, "user", updatedUser.ID, before, updatedUser); err != nil {
+			return nil, err
+		}
+		if err := s.publish(ctx, UserUpdated{EventMeta: s.eventMeta(ctx, updatedUser.ID)}); err != nil {
+			return nil, err
+		}
+		return updatedUser, nil
+	})
 }
 
 func (s *Service) ListUsers(ctx context.Context) ([]User, error) {
+	if err := s.authorize(ctx, PermissionUsersList, Resource{Type: "user"}); err != nil {
+		return nil, err
+	}
+
 	users, err := s.userRepo.List(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
 	}
-	return users, nil
+
+	active := make([]User, 0, len(users))
+	for _, user := range users {
+		if user.DeletedAt == nil {
+			active = append(active, user)
+		}
+	}
+	return active, nil
 }
 
 func (s *Service) DeleteUser(ctx context.Context, id string) error {
-	err := s.userRepo.Delete(ctx, id)
+	if err := s.authorizeUser(ctx, PermissionUsersDelete, id); err != nil {
+		return err
+	}
+
+	return s.withinTx(ctx, func(ctx context.Context) error {
+		user, err := s.activeUser(ctx, id)
+		if err != nil {
+			return ErrUserNotFound
+		}
+
+		deleted := *user
+		deletedAt := s.now()
+		deleted.DeletedAt = &deletedAt
+		_, err = s.userRepo.Update(ctx, deleted)
+		if err != nil {
+			return updateError(ErrFailedToDeleteUser, err)
+		}
+
+		if err := s.audit(ctx, AuditUserDeleted, "user", id, user, deleted); err != nil {
+			return err
+		}
+		return s.publish(ctx, UserDeleted{EventMeta: s.eventMeta(ctx, id)})
+	})
+}
+
+func (s *Service) RestoreUser(ctx context.Context, id string) (*User, error) {
+	if err := s.authorizeUser(ctx, PermissionUsersDelete, id); err != nil {
+		return nil, err
+	}
+
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		user, err := s.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		if user.DeletedAt == nil {
+			return nil, ErrUserNotDeleted
+		}
+
+		before := *user
+		user.DeletedAt = nil
+		restoredUser, err := s.userRepo.Update(ctx, *user)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateUser, err)
+		}
+
+		if err := s.audit(ctx, AuditUserRestored, "user", id, before, restoredUser); err != nil {
+			return nil, err
+		}
+		if err := s.publish(ctx, UserRestored{EventMeta: s.eventMeta(ctx, id)}); err != nil {
+			return nil, err
+		}
+		return restoredUser, nil
+	})
+}
+
+// PurgeDeletedUsers permanently removes users that were soft-deleted more
+// than olderThan ago and returns how many were removed.
+func (s *Service) PurgeDeletedUsers(ctx context.Context, olderThan time.Duration) (int, error) {
+	if err := s.authorize(ctx, PermissionUsersPurge, Resource{Type: "user"}); err != nil {
+		return 0, err
+	}
+
+	users, err := s.userRepo.List(ctx)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrFailedToDeleteUser, err)
+		return 0, fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
 	}
-	return nil
+
+	cutoff := s.now().Add(-olderThan)
+	purged := 0
+	for _, user := range users {
+		if user.DeletedAt == nil || user.DeletedAt.After(cutoff) {
+			continue
+		}
+		err := s.withinTx(ctx, func(ctx context.Context) error {
+			if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+				return fmt.Errorf("%w: %v", ErrFailedToDeleteUser, err)
+			}
+			if err := s.audit(ctx, AuditUserPurged, "user", user.ID, user, nil); err != nil {
+				return err
+			}
+			return s.publish(ctx, UserPurged{EventMeta: s.eventMeta(ctx, user.ID)})
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
 }
 
 func (s *Service) GetRoleByID(ctx context.Context, id string) (*Role, error) {
+	if err := s.authorizeRole(ctx, PermissionRolesRead, id); err != nil {
+		return nil, err
+	}
+
 	role, err := s.roleRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, ErrRoleNotFound
@@ -115,34 +360,101 @@ func (s *Service) GetRoleByID(ctx context.Context, id string) (*Role, error) {
 }
 
 func (s *Service) CreateRole(ctx context.Context, role Role) (*Role, error) {
-	createdRole, err := s.roleRepo.Create(ctx, role)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrFailedToCreateRole, err)
+	if err := s.authorizeRole(ctx, PermissionRolesCreate, role.ID); err != nil {
+		return nil, err
 	}
-	return createdRole, nil
+
+	return inTx(ctx, s, func(ctx context.Context) (*Role, error) {
+		if err := s.validateRoleParents(ctx, role); err != nil {
+			return nil, err
+		}
+
+		createdRole, err := s.roleRepo.Create(ctx, role)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToCreateRole, err)
+		}
+
+		if err := s.audit(ctx, AuditRoleCreated, "role", createdRole.ID, nil, createdRole); err != nil {
+			return nil, err
+		}
+		return createdRole, nil
+	})
 }
 
-func (s *Service) AssignRoleToUser(ctx context.Context, userID, roleID string) (*User, error) {
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return nil, ErrUserNotFound
+func (s *Service) UpdateRole(ctx context.Context, role Role) (*Role, error) {
+	if err := s.authorizeRole(ctx, PermissionRolesUpdate, role.ID); err != nil {
+		return nil, err
 	}
 
-	role, err := s.roleRepo.GetByID(ctx, roleID)
-	if err != nil {
-		return nil, ErrRoleNotFound
-	}
+	return inTx(ctx, s, func(ctx context.Context) (*Role, error) {
+		existing, err := s.roleRepo.GetByID(ctx, role.ID)
+		if err != nil {
+			return nil, ErrRoleNotFound
+		}
+		if role.Version != existing.Version {
+			return nil, ErrConflict
+		}
+		before := *existing
 
-	user.RoleID = role.ID
-	updatedUser, err := s.userRepo.Update(ctx, *user)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrFailedToUpdateUser, err)
+		if err := s.validateRoleParents(ctx, role); err != nil {
+			return nil, err
+		}
+
+		updatedRole, err := s.roleRepo.Update(ctx, role)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateRole, err)
+		}
+
+		if err := s.audit(ctx, AuditRoleUpdated, "role", updatedRole.ID, before, updatedRole); err != nil {
+			return nil, err
+		}
+		return updatedRole, nil
+	})
+}
+
+func (s *Service) AssignRoleToUser(ctx context.Context, userID, roleID string) (*User, error) {
+	if err := s.authorizeUser(ctx, PermissionRolesAssign, userID); err != nil {
+		return nil, err
 	}
 
-	return updatedUser, nil
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		user, err := s.activeUser(ctx, userID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+
+		role, err := s.roleRepo.GetByID(ctx, roleID)
+		if err != nil {
+			return nil, ErrRoleNotFound
+		}
+
+		before := *user
+		user.RoleID = role.ID
+		updatedUser, err := s.userRepo.Update(ctx, *user)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateUser, err)
+		}
+
+		if err := s.audit(ctx, AuditRoleAssigned, "user", userID, before, updatedUser); err != nil {
+			return nil, err
+		}
+		err = s.publish(ctx, RoleAssigned{
+			EventMeta:      s.eventMeta(ctx, userID),
+			RoleID:         role.ID,
+			PreviousRoleID: before.RoleID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return updatedUser, nil
+	})
 }
 
 func (s *Service) ListRoles(ctx context.Context) ([]Role, error) {
+	if err := s.authorizeRole(ctx, PermissionRolesRead, ""); err != nil {
+		return nil, err
+	}
+
 	roles, err := s.roleRepo.List(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
@@ -164,30 +476,41 @@ func (s *Service) IsAdmin(user *User) bool {
 }
 
 func (s *Service) UpdateLastSeen(ctx context.Context, userID string) error {
-	user, err := s.userRepo.GetByID(ctx, userID)
+	if err := s.authorizeUser(ctx, PermissionUsersUpdate, userID); err != nil {
+		return err
+	}
+
+	user, err := s.activeUser(ctx, userID)
 	if err != nil {
 		return ErrUserNotFound
 	}
 
-	user.LastSeen = time.Now()
+	user.LastSeen = s.now()
 	_, err = s.userRepo.Update(ctx, *user)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrFailedToUpdateUser, err)
+		return updateError(ErrFailedToUpdateUser, err)
 	}
 
 	return nil
 }
 
 func (s *Service) ChangePassword(ctx context.Context, userID, oldPassword, newPassword string) (*User, error) {
-	user, err := s.userRepo.GetByID(ctx, userID)
+	if err := s.authorizeUser(ctx, PermissionUsersUpdate, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.activeUser(ctx, userID)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
 
 		// ...existing code...
 
-	if oldPassword == newPassword {
-		return nil, ErrCannotUseSamePassword
+	switch {
+	case newPassword == "":
+		return nil, invalidField("new_password", CodeRequired, ErrInvalidPassword, "is required")
+	case oldPassword == newPassword:
+		return nil, invalidField("new_password", CodeSameAsCurrent, ErrCannotUseSamePassword, "must differ from the current password")
 	}
 
 	if !s.hasher.Verify(user.HashedPassword, oldPassword) {
@@ -200,17 +523,30 @@ func (s *Service) ChangePassword(ctx context.Context, userID, oldPassword, newPa
 		return nil, fmt.Errorf("%w: %v", ErrFailedToHashPassword, err)
 	}
 
-	user.HashedPassword = hashedNewPassword
-	updatedUser, err := s.userRepo.Update(ctx, *user)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrFailedToUpdateUser, err)
-	}
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		before := *user
+		user.HashedPassword = hashedNewPassword
+		updatedUser, err := s.userRepo.Update(ctx, *user)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateUser, err)
+		}
 
-	return updatedUser, nil
+		if err := s.audit(ctx, AuditPasswordChanged, "user", userID, before, updatedUser); err != nil {
+			return nil, err
+		}
+		if err := s.publish(ctx, PasswordChanged{EventMeta: s.eventMeta(ctx, userID)}); err != nil {
+			return nil, err
+		}
+		return updatedUser, nil
+	})
 }
 
 func (s *Service) ResetPassword(ctx context.Context, userID, newPassword string) (*User, error) {
-	user, err := s.userRepo.GetByID(ctx, userID)
+	if err := s.authorizeUser(ctx, PermissionUsersResetPassword, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.activeUser(ctx, userID)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -220,11 +556,38 @@ func (s *Service) ResetPassword(ctx context.Context, userID, newPassword string)
 		return nil, fmt.Errorf("%w: %v", ErrFailedToHashPassword, err)
 	}
 
-	user.HashedPassword = hashedPassword
-	updatedUser, err := s.userRepo.Update(ctx, *user)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrFailedToUpdateUser, err)
+	return inTx(ctx, s, func(ctx context.Context) (*User, error) {
+		before := *user
+		user.HashedPassword = hashedPassword
+		updatedUser, err := s.userRepo.Update(ctx, *user)
+		if err != nil {
+			return nil, updateError(ErrFailedToUpdateUser, err)
+		}
+
+		if err := s.audit(ctx, AuditPasswordReset, "user", userID, before, updatedUser); err != nil {
+			return nil, err
+		}
+		if err := s.publish(ctx, PasswordChanged{EventMeta: s.eventMeta(ctx, userID), Reset: true}); err != nil {
+			return nil, err
+		}
+		return updatedUser, nil
+	})
+}
+
+// updateError wraps a failed repository update in sentinel. ErrConflict is
+// returned as is so that callers can tell a stale write apart and retry.
+func updateError(sentinel, err error) error {
+	if errors.Is(err, ErrConflict) {
+		return err
 	}
+	return fmt.Errorf("%w: %v", sentinel, err)
+}
 
-	return updatedUser, nil
+// activeUser loads a user, treating soft-deleted accounts as missing.
+func (s *Service) activeUser(ctx context.Context, id string) (*User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil || user.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
 }
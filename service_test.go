@@ -346,10 +346,11 @@ func TestDeleteUser(t *testing.T) {
 	})
 
 	t.Run("fail", func(t *testing.T) {
-		userRepo.deleteErr = errors.New("fail")
+		userRepo.users["u1"].DeletedAt = nil
+		userRepo.updateErr = errors.New("fail")
 		err := svc.DeleteUser(ctx, "u1")
 		require.ErrorIs(t, err, ErrFailedToDeleteUser)
-		userRepo.deleteErr = nil
+		userRepo.updateErr = nil
 	})
 }
 
@@ -498,3 +499,150 @@ func TestChangePassword(t *testing.T) {
 	})
 
 }
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"u1": {ID: "u1", Email: "a@example.com", HashedPassword: "hashed:pw"},
+		"u2": {ID: "u2", Email: "b@example.com", HashedPassword: "hashed:pw"},
+	}}
+	svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+
+	require.NoError(t, svc.DeleteUser(ctx, "u1"))
+	require.Contains(t, userRepo.users, "u1")
+	require.NotNil(t, userRepo.users["u1"].DeletedAt)
+
+	t.Run("hidden from reads", func(t *testing.T) {
+		_, err := svc.GetUserByID(ctx, "u1")
+		require.ErrorIs(t, err, ErrUserNotFound)
+
+		us, err := svc.ListUsers(ctx)
+		require.NoError(t, err)
+		require.Len(t, us, 1)
+		require.Equal(t, "u2", us[0].ID)
+
+		_, err = svc.Login(ctx, UserLoginInput{Email: "a@example.com", Password: "pw"})
+		require.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("delete twice", func(t *testing.T) {
+		require.ErrorIs(t, svc.DeleteUser(ctx, "u1"), ErrUserNotFound)
+	})
+
+	t.Run("email stays reserved", func(t *testing.T) {
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "a@example.com", Username: "a", Password: "pw"})
+		require.ErrorIs(t, err, ErrEmailTaken)
+	})
+}
+
+func TestRestoreUser(t *testing.T) {
+	ctx := context.Background()
+	deletedAt := time.Now()
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"u1": {ID: "u1", DeletedAt: &deletedAt},
+		"u2": {ID: "u2"},
+	}}
+	svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+
+	t.Run("success", func(t *testing.T) {
+		u, err := svc.RestoreUser(ctx, "u1")
+		require.NoError(t, err)
+		require.Nil(t, u.DeletedAt)
+		_, err = svc.GetUserByID(ctx, "u1")
+		require.NoError(t, err)
+	})
+
+	t.Run("not deleted", func(t *testing.T) {
+		_, err := svc.RestoreUser(ctx, "u2")
+		require.ErrorIs(t, err, ErrUserNotDeleted)
+	})
+
+	t.Run("update cannot restore", func(t *testing.T) {
+		require.NoError(t, svc.DeleteUser(ctx, "u2"))
+		u2 := *userRepo.users["u2"]
+		u2.DeletedAt = nil
+		_, err := svc.UpdateUser(ctx, u2)
+		require.NoError(t, err)
+		require.NotNil(t, userRepo.users["u2"].DeletedAt)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := svc.RestoreUser(ctx, "notfound")
+		require.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-40 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	userRepo := &mockUserRepo{users: map[string]*User{
+		"old":    {ID: "old", DeletedAt: &old},
+		"recent": {ID: "recent", DeletedAt: &recent},
+		"active": {ID: "active"},
+	}}
+	svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{}, WithClock(func() time.Time { return now }))
+
+	t.Run("success", func(t *testing.T) {
+		n, err := svc.PurgeDeletedUsers(ctx, 30*24*time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		require.NotContains(t, userRepo.users, "old")
+		require.Contains(t, userRepo.users, "recent")
+		require.Contains(t, userRepo.users, "active")
+	})
+
+	t.Run("delete fails", func(t *testing.T) {
+		userRepo.deleteErr = errors.New("fail")
+		_, err := svc.PurgeDeletedUsers(ctx, 0)
+		require.ErrorIs(t, err, ErrFailedToDeleteUser)
+		userRepo.deleteErr = nil
+	})
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	ctx := context.Background()
+	userRepo := NewMemoryUserRepository()
+	roleRepo := NewMemoryRoleRepository()
+	svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{})
+
+	user, err := svc.Register(ctx, UserRegisterInput{Email: "a@example.com", Username: "a", Password: "pw"})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, user.Version)
+
+	t.Run("stale user update", func(t *testing.T) {
+		first, second := *user, *user
+		first.Username = "first"
+		updated, err := svc.UpdateUser(ctx, first)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, updated.Version)
+
+		second.Username = "second"
+		_, err = svc.UpdateUser(ctx, second)
+		require.ErrorIs(t, err, ErrConflict)
+
+		stored, err := userRepo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "first", stored.Username)
+	})
+
+	t.Run("write between read and update", func(t *testing.T) {
+		stale, err := userRepo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		_, err = svc.AssignRoleToUser(ctx, user.ID, user.RoleID)
+		require.NoError(t, err)
+
+		_, err = userRepo.Update(ctx, *stale)
+		require.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("stale role update", func(t *testing.T) {
+		role, err := svc.CreateRole(ctx, Role{Name: "editor"})
+		require.NoError(t, err)
+		_, err = svc.UpdateRole(ctx, Role{ID: role.ID, Name: "writer", Version: role.Version})
+		require.NoError(t, err)
+		_, err = svc.UpdateRole(ctx, Role{ID: role.ID, Name: "author", Version: role.Version})
+		require.ErrorIs(t, err, ErrConflict)
+	})
+}
@@ -0,0 +1,174 @@
+package users
+
+import (
+	"context"
+	"sync"
+)
+
+// TxManager runs a unit of work atomically. Repositories join the transaction
+// through the context passed to fn, so fn must use that context for every
+// repository call. WithinTx called with a context that already carries a
+// transaction joins it instead of starting a new one.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+func WithTxManager(txManager TxManager) ServiceOption {
+	return func(s *Service) {
+		s.txManager = txManager
+	}
+}
+
+// withinTx runs fn in a transaction, or directly when no TxManager is
+// configured. Side effects registered with afterCommit inside fn run once
+// the outermost withinTx has committed, and are dropped if it rolls back.
+func (s *Service) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.txManager == nil {
+		return fn(ctx)
+	}
+	if _, ok := ctx.Value(txEffectsKey{}).(*txEffects); ok {
+		return s.txManager.WithinTx(ctx, fn)
+	}
+
+	effects := &txEffects{}
+	if err := s.txManager.WithinTx(context.WithValue(ctx, txEffectsKey{}, effects), fn); err != nil {
+		return err
+	}
+	effects.run(ctx)
+	return nil
+}
+
+type txEffectsKey struct{}
+
+type txEffects struct {
+	mu      sync.Mutex
+	effects []func(ctx context.Context)
+}
+
+func (e *txEffects) run(ctx context.Context) {
+	e.mu.Lock()
+	effects := e.effects
+	e.effects = nil
+	e.mu.Unlock()
+
+	for _, effect := range effects {
+		effect(ctx)
+	}
+}
+
+// afterCommit defers effect until the transaction carried by ctx commits. It
+// is for work that cannot be rolled back, such as notifying subscribers;
+// outside a transaction effect runs right away. Deferred effects get the
+// context withinTx was called with, not the transaction's.
+func afterCommit(ctx context.Context, effect func(ctx context.Context)) {
+	e, ok := ctx.Value(txEffectsKey{}).(*txEffects)
+	if !ok {
+		effect(ctx)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.effects = append(e.effects, effect)
+}
+
+// inTx is withinTx for operations that produce a result.
+func inTx[T any](ctx context.Context, s *Service, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// MemoryTxManager is the TxManager for the in-memory repositories. Writes
+// they make inside WithinTx are undone, newest first, when fn returns an error
+// or panics; the audit log holds its entries back until fn succeeds. Transactions run one at a time, but readers and writes made
+// outside a transaction can still observe uncommitted state.
+type MemoryTxManager struct {
+	mu sync.Mutex
+}
+
+func NewMemoryTxManager() *MemoryTxManager {
+	return &MemoryTxManager{}
+}
+
+type memoryTxKey struct{}
+
+type memoryTx struct {
+	mu     sync.Mutex
+	undo   []func()
+	commit []func()
+}
+
+func (m *MemoryTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &memoryTx{}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback()
+			panic(r)
+		}
+		if err != nil {
+			tx.rollback()
+		}
+	}()
+	if err = fn(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		return err
+	}
+	tx.committed()
+	return nil
+}
+
+func (tx *memoryTx) committed() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	for _, fn := range tx.commit {
+		fn()
+	}
+	tx.undo, tx.commit = nil, nil
+}
+
+func (tx *memoryTx) rollback() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo, tx.commit = nil, nil
+}
+
+// onRollback registers undo to run if the memory transaction carried by ctx
+// rolls back. Outside a transaction it does nothing.
+func onRollback(ctx context.Context, undo func()) {
+	tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx)
+	if !ok {
+		return
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.undo = append(tx.undo, undo)
+}
+
+// onCommit registers fn to run once the memory transaction carried by ctx
+// commits, and reports whether there is one. Outside a transaction it does
+// nothing and returns false.
+func onCommit(ctx context.Context, fn func()) bool {
+	tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx)
+	if !ok {
+		return false
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.commit = append(tx.commit, fn)
+	return true
+}
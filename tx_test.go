@@ -0,0 +1,138 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingCreateUserRepo struct {
+	*MemoryUserRepository
+}
+
+func (failingCreateUserRepo) Create(ctx context.Context, user User) (*User, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestMemoryTxManager(t *testing.T) {
+	ctx := context.Background()
+	txManager := NewMemoryTxManager()
+	users := NewMemoryUserRepository()
+	outbox := NewMemoryOutbox()
+
+	t.Run("commit", func(t *testing.T) {
+		err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+			_, err := users.Create(ctx, User{ID: "kept"})
+			return err
+		})
+		require.NoError(t, err)
+		_, err = users.GetByID(ctx, "kept")
+		require.NoError(t, err)
+	})
+
+	t.Run("rollback undoes every write", func(t *testing.T) {
+		failure := errors.New("boom")
+		err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+			if _, err := users.Create(ctx, User{ID: "new"}); err != nil {
+				return err
+			}
+			if _, err := users.Update(ctx, User{ID: "kept", Username: "changed", Version: 1}); err != nil {
+				return err
+			}
+			if err := outbox.Append(ctx, OutboxMessage{Type: EventUserUpdated}); err != nil {
+				return err
+			}
+			// Nested calls join the outer transaction.
+			return txManager.WithinTx(ctx, func(ctx context.Context) error {
+				if err := users.Delete(ctx, "kept"); err != nil {
+					return err
+				}
+				return failure
+			})
+		})
+		require.ErrorIs(t, err, failure)
+
+		_, err = users.GetByID(ctx, "new")
+		require.ErrorIs(t, err, ErrUserNotFound)
+		kept, err := users.GetByID(ctx, "kept")
+		require.NoError(t, err)
+		require.Empty(t, kept.Username)
+		require.EqualValues(t, 1, kept.Version)
+		pending, err := outbox.ListByStatus(ctx, OutboxPending, "", 0)
+		require.NoError(t, err)
+		require.Empty(t, pending)
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		require.Panics(t, func() {
+			_ = txManager.WithinTx(ctx, func(ctx context.Context) error {
+				_, _ = users.Create(ctx, User{ID: "panicked"})
+				panic("boom")
+			})
+		})
+		_, err := users.GetByID(ctx, "panicked")
+		require.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestServiceTransactions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("register leaves no role behind", func(t *testing.T) {
+		roleRepo := NewMemoryRoleRepository()
+		userRepo := failingCreateUserRepo{NewMemoryUserRepository()}
+		svc := NewService(userRepo, roleRepo, &mockHasher{}, &mockTokenizer{}, WithTxManager(NewMemoryTxManager()))
+
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "a@example.com", Username: "a", Password: "pw"})
+		require.Error(t, err)
+
+		roles, err := roleRepo.List(ctx)
+		require.NoError(t, err)
+		require.Empty(t, roles)
+	})
+
+	t.Run("failed audit undoes the update", func(t *testing.T) {
+		userRepo := NewMemoryUserRepository()
+		svc := NewService(userRepo, NewMemoryRoleRepository(), &mockHasher{}, &mockTokenizer{}, WithTxManager(NewMemoryTxManager()))
+		user, err := svc.Register(ctx, UserRegisterInput{Email: "a@example.com", Username: "a", Password: "pw"})
+		require.NoError(t, err)
+		svc.auditLog = &failingAuditLog{}
+
+		_, err = svc.ResetPassword(ctx, user.ID, "new")
+		require.ErrorIs(t, err, ErrFailedToWriteAudit)
+
+		stored, err := userRepo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "hashed:pw", stored.HashedPassword)
+		require.Equal(t, user.Version, stored.Version)
+	})
+
+	t.Run("events wait for the commit", func(t *testing.T) {
+		events := &recordingPublisher{}
+		index := NewMemoryUserSearchIndex()
+		svc := NewService(NewMemoryUserRepository(), NewMemoryRoleRepository(), &mockHasher{}, &mockTokenizer{},
+			WithTxManager(NewMemoryTxManager()), WithEventPublisher(events), WithUserSearchIndex(index))
+
+		failure := errors.New("membership failed")
+		err := svc.withinTx(ctx, func(ctx context.Context) error {
+			_, err := svc.Register(ctx, UserRegisterInput{Email: "a@example.com", Username: "alice", Password: "pw"})
+			require.NoError(t, err)
+			require.Empty(t, events.events, "not before the outer commit")
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+		require.Empty(t, events.events)
+		hits, err := index.Search(ctx, "alice", 10)
+		require.NoError(t, err)
+		require.Empty(t, hits)
+
+		_, err = svc.Register(ctx, UserRegisterInput{Email: "a@example.com", Username: "alice", Password: "pw"})
+		require.NoError(t, err)
+		require.Equal(t, []string{EventUserRegistered}, events.types())
+		hits, err = index.Search(ctx, "alice", 10)
+		require.NoError(t, err)
+		require.Len(t, hits, 1)
+	})
+}
@@ -9,6 +9,20 @@ type User struct {
 	Email          string
 	HashedPassword string
 	Username       string
+	DisplayName    string
 	LastSeen       time.Time
 	RoleID         string
+	CreatedAt      time.Time
+	Attributes     map[string]string
+	DeletedAt      *time.Time
+	ErasedAt       *time.Time
+
+	Status          UserStatus
+	StatusReason    string
+	StatusActorID   string
+	StatusExpiresAt *time.Time
+
+	// Version is bumped by the repository on every update. Updates carrying
+	// a stale Version fail with ErrConflict.
+	Version int64
 }
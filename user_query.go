@@ -0,0 +1,287 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type UserSortField string
+
+const (
+	SortByCreatedAt UserSortField = "created_at"
+	SortByLastSeen  UserSortField = "last_seen"
+	SortByEmail     UserSortField = "email"
+	SortByUsername  UserSortField = "username"
+)
+
+const (
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 500
+)
+
+// ListUsersQuery selects a page of users. Zero-valued filters do not filter;
+// the After bounds are inclusive and the Before bounds exclusive. Results are
+// ordered by SortBy (CreatedAt by default) with ID breaking ties, and
+// soft-deleted users are never included. Status matches the status a user
+// is effectively in at Now, so a suspension that has run out counts as
+// active; QueryUsers sets Now from the Service clock, and a zero Now means
+// the current time.
+type ListUsersQuery struct {
+	RoleID         string
+	Status         UserStatus
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	LastSeenAfter  time.Time
+	LastSeenBefore time.Time
+	EmailDomain    string
+	Now            time.Time
+
+	SortBy     UserSortField
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+// UserPage is one page of users. TotalCount counts every user matching the
+// filters, not just this page; NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []User
+	NextCursor string
+	TotalCount int
+}
+
+// UserQuerier is implemented by user repositories that can filter, sort and
+// paginate in storage. Service falls back to UserRepository.List and
+// FilterUsers for repositories that do not implement it.
+type UserQuerier interface {
+	QueryUsers(ctx context.Context, q ListUsersQuery) (*UserPage, error)
+}
+
+// userCursor is the position a page ends at. It records the sort order too,
+// so a cursor cannot be replayed against a differently sorted query.
+type userCursor struct {
+	SortBy     UserSortField `json:"s"`
+	Descending bool          `json:"d"`
+	ID         string        `json:"id"`
+	Value      string        `json:"v"`
+}
+
+func (q ListUsersQuery) sortField() UserSortField {
+	if q.SortBy == "" {
+		return SortByCreatedAt
+	}
+	return q.SortBy
+}
+
+func (q ListUsersQuery) validate() error {
+	switch q.sortField() {
+	case SortByCreatedAt, SortByLastSeen, SortByEmail, SortByUsername:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown sort field %q", ErrInvalidUserQuery, q.SortBy)
+}
+
+func (q ListUsersQuery) matches(user User) bool {
+	switch {
+	case user.DeletedAt != nil:
+		return false
+	case q.RoleID != "" && user.RoleID != q.RoleID:
+		return false
+	case q.Status != "" && userStatus(user, q.now()) != q.Status:
+		return false
+	case !q.CreatedAfter.IsZero() && user.CreatedAt.Before(q.CreatedAfter):
+		return false
+	case !q.CreatedBefore.IsZero() && !user.CreatedAt.Before(q.CreatedBefore):
+		return false
+	case !q.LastSeenAfter.IsZero() && user.LastSeen.Before(q.LastSeenAfter):
+		return false
+	case !q.LastSeenBefore.IsZero() && !user.LastSeen.Before(q.LastSeenBefore):
+		return false
+	case q.EmailDomain != "" && !strings.EqualFold(emailDomain(user.Email), strings.TrimPrefix(q.EmailDomain, "@")):
+		return false
+	}
+	return true
+}
+
+func (q ListUsersQuery) now() time.Time {
+	if q.Now.IsZero() {
+		return time.Now()
+	}
+	return q.Now
+}
+
+// userStatus is the status that IsRestricted enforces at now: users that
+// never had a status set or whose suspension has run out are active.
+func userStatus(user User, now time.Time) UserStatus {
+	if !user.IsRestricted(now) {
+		return StatusActive
+	}
+	return user.Status
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return email[at+1:]
+}
+
+func compareUsers(a, b User, field UserSortField) int {
+	var c int
+	switch field {
+	case SortByLastSeen:
+		c = a.LastSeen.Compare(b.LastSeen)
+	case SortByEmail:
+		c = strings.Compare(a.Email, b.Email)
+	case SortByUsername:
+		c = strings.Compare(a.Username, b.Username)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+func sortValue(user User, field UserSortField) string {
+	switch field {
+	case SortByLastSeen:
+		return user.LastSeen.Format(time.RFC3339Nano)
+	case SortByEmail:
+		return user.Email
+	case SortByUsername:
+		return user.Username
+	}
+	return user.CreatedAt.Format(time.RFC3339Nano)
+}
+
+func encodeUserCursor(user User, q ListUsersQuery) string {
+	data, _ := json.Marshal(userCursor{
+		SortBy:     q.sortField(),
+		Descending: q.Descending,
+		ID:         user.ID,
+		Value:      sortValue(user, q.sortField()),
+	})
+	return encodeCursor(string(data))
+}
+
+// decodeUserCursor returns a stand-in for the last user of the previous page
+// carrying just the fields compareUsers looks at.
+func decodeUserCursor(cursor string, q ListUsersQuery) (*User, error) {
+	position, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var c userCursor
+	if err := json.Unmarshal([]byte(position), &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy != q.sortField() || c.Descending != q.Descending {
+		return nil, fmt.Errorf("%w: cursor belongs to a different sort order", ErrInvalidCursor)
+	}
+
+	pivot := &User{ID: c.ID}
+	switch c.SortBy {
+	case SortByEmail:
+		pivot.Email = c.Value
+	case SortByUsername:
+		pivot.Username = c.Value
+	default:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		pivot.CreatedAt, pivot.LastSeen = t, t
+	}
+	return pivot, nil
+}
+
+// FilterUsers applies q to users in memory. It is the reference behaviour for
+// UserQuerier implementations.
+func FilterUsers(users []User, q ListUsersQuery) (*UserPage, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	var pivot *User
+	if q.Cursor != "" {
+		var err error
+		if pivot, err = decodeUserCursor(q.Cursor, q); err != nil {
+			return nil, err
+		}
+	}
+
+	field := q.sortField()
+	matched := make([]User, 0, len(users))
+	for _, user := range users {
+		if q.matches(user) {
+			matched = append(matched, user)
+		}
+	}
+	slices.SortFunc(matched, func(a, b User) int {
+		if q.Descending {
+			return compareUsers(b, a, field)
+		}
+		return compareUsers(a, b, field)
+	})
+
+	page := &UserPage{Users: []User{}, TotalCount: len(matched)}
+	limit := userPageSize(q.Limit)
+	for _, user := range matched {
+		if pivot != nil {
+			c := compareUsers(user, *pivot, field)
+			if (!q.Descending && c <= 0) || (q.Descending && c >= 0) {
+				continue
+			}
+		}
+		if len(page.Users) == limit {
+			page.NextCursor = encodeUserCursor(page.Users[limit-1], q)
+			break
+		}
+		page.Users = append(page.Users, user)
+	}
+	return page, nil
+}
+
+func userPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultUserPageSize
+	}
+	return min(limit, MaxUserPageSize)
+}
+
+// QueryUsers is the paginated variant of ListUsers.
+func (s *Service) QueryUsers(ctx context.Context, q ListUsersQuery) (*UserPage, error) {
+	if err := s.authorize(ctx, PermissionUsersList, Resource{Type: "user"}); err != nil {
+		return nil, err
+	}
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	if q.Now.IsZero() {
+		q.Now = s.now()
+	}
+
+	if querier, ok := s.userRepo.(UserQuerier); ok {
+		page, err := querier.QueryUsers(ctx, q)
+		if errors.Is(err, ErrInvalidCursor) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
+		}
+		return page, nil
+	}
+
+	users, err := s.userRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
+	}
+	return FilterUsers(users, q)
+}
@@ -0,0 +1,159 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newQueryFixture(t *testing.T) *MemoryUserRepository {
+	t.Helper()
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	deletedAt := start
+	repo := NewMemoryUserRepository()
+	fixtures := []User{
+		{ID: "u1", Email: "ann@acme.com", Username: "ann", RoleID: "r-user", CreatedAt: start, LastSeen: start.Add(48 * time.Hour)},
+		{ID: "u2", Email: "bob@Acme.com", Username: "bob", RoleID: "r-admin", CreatedAt: start.Add(time.Hour), LastSeen: start.Add(2 * time.Hour)},
+		{ID: "u3", Email: "cat@example.com", Username: "cat", RoleID: "r-user", CreatedAt: start.Add(2 * time.Hour), Status: StatusBanned},
+		{ID: "u4", Email: "dan@acme.com", Username: "dan", RoleID: "r-user", CreatedAt: start.Add(2 * time.Hour), Status: StatusActive},
+		{ID: "u5", Email: "eve@acme.com", Username: "eve", RoleID: "r-user", CreatedAt: start.Add(3 * time.Hour), DeletedAt: &deletedAt},
+	}
+	for _, user := range fixtures {
+		_, err := repo.Create(ctx, user)
+		require.NoError(t, err)
+	}
+	return repo
+}
+
+func userIDs(users []User) []string {
+	var ids []string
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func TestFilterUsers(t *testing.T) {
+	repo := newQueryFixture(t)
+	users, err := repo.List(context.Background())
+	require.NoError(t, err)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query ListUsersQuery
+		want  []string
+	}{
+		{"default order skips deleted", ListUsersQuery{}, []string{"u1", "u2", "u3", "u4"}},
+		{"role", ListUsersQuery{RoleID: "r-admin"}, []string{"u2"}},
+		{"active includes unset status", ListUsersQuery{Status: StatusActive}, []string{"u1", "u2", "u4"}},
+		{"banned", ListUsersQuery{Status: StatusBanned}, []string{"u3"}},
+		{"created range", ListUsersQuery{CreatedAfter: start.Add(time.Hour), CreatedBefore: start.Add(2 * time.Hour)}, []string{"u2"}},
+		{"last seen", ListUsersQuery{LastSeenAfter: start.Add(24 * time.Hour)}, []string{"u1"}},
+		{"email domain ignores case", ListUsersQuery{EmailDomain: "@acme.com"}, []string{"u1", "u2", "u4"}},
+		{"descending email", ListUsersQuery{SortBy: SortByEmail, Descending: true}, []string{"u4", "u3", "u2", "u1"}},
+		{"username", ListUsersQuery{SortBy: SortByUsername}, []string{"u1", "u2", "u3", "u4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := FilterUsers(users, tt.query)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, userIDs(page.Users))
+			require.Equal(t, len(tt.want), page.TotalCount)
+		})
+	}
+
+	t.Run("expired suspension counts as active", func(t *testing.T) {
+		until := start.Add(48 * time.Hour)
+		suspended := []User{{ID: "s1", Status: StatusSuspended, StatusExpiresAt: &until}}
+
+		page, err := FilterUsers(suspended, ListUsersQuery{Status: StatusSuspended, Now: start})
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1"}, userIDs(page.Users))
+
+		page, err = FilterUsers(suspended, ListUsersQuery{Status: StatusSuspended, Now: until})
+		require.NoError(t, err)
+		require.Empty(t, page.Users)
+		page, err = FilterUsers(suspended, ListUsersQuery{Status: StatusActive, Now: until})
+		require.NoError(t, err)
+		require.Equal(t, []string{"s1"}, userIDs(page.Users))
+	})
+
+	t.Run("unknown sort field", func(t *testing.T) {
+		_, err := FilterUsers(users, ListUsersQuery{SortBy: "password"})
+		require.ErrorIs(t, err, ErrInvalidUserQuery)
+	})
+}
+
+func TestQueryUsersPagination(t *testing.T) {
+	svc := NewService(newQueryFixture(t), &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+	ctx := context.Background()
+
+	for _, descending := range []bool{false, true} {
+		t.Run(fmt.Sprintf("descending=%v", descending), func(t *testing.T) {
+			q := ListUsersQuery{Limit: 1, Descending: descending}
+			var ids []string
+			for {
+				page, err := svc.QueryUsers(ctx, q)
+				require.NoError(t, err)
+				require.Equal(t, 4, page.TotalCount)
+				ids = append(ids, userIDs(page.Users)...)
+				if page.NextCursor == "" {
+					break
+				}
+				q.Cursor = page.NextCursor
+			}
+			// u3 and u4 share a creation time; the ID breaks the tie.
+			want := []string{"u1", "u2", "u3", "u4"}
+			if descending {
+				want = []string{"u4", "u3", "u2", "u1"}
+			}
+			require.Equal(t, want, ids)
+		})
+	}
+
+	t.Run("cursor from another sort order", func(t *testing.T) {
+		page, err := svc.QueryUsers(ctx, ListUsersQuery{Limit: 1})
+		require.NoError(t, err)
+		_, err = svc.QueryUsers(ctx, ListUsersQuery{Limit: 1, SortBy: SortByEmail, Cursor: page.NextCursor})
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("repository without querier", func(t *testing.T) {
+		userRepo := &mockUserRepo{users: map[string]*User{
+			"a": {ID: "a", RoleID: "r-user"},
+			"b": {ID: "b", RoleID: "r-admin"},
+		}}
+		svc := NewService(userRepo, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		page, err := svc.QueryUsers(ctx, ListUsersQuery{RoleID: "r-user"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a"}, userIDs(page.Users))
+	})
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+
+	created, err := repo.Create(ctx, User{Email: "a@example.com", Username: "a"})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.ID)
+
+	created.Username = "changed"
+	stored, err := repo.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "a", stored.Username, "returned users are copies")
+
+	_, err = repo.GetByEmail(ctx, "missing@example.com")
+	require.ErrorIs(t, err, ErrUserNotFound)
+	_, err = repo.Update(ctx, User{ID: "missing"})
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	require.NoError(t, repo.Delete(ctx, created.ID))
+	_, err = repo.GetByUsername(ctx, "a")
+	require.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -1,7 +1,16 @@
 package users
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+)
 
+// UserRepository stores users. Create stores version 1 and Update must fail
+// with ErrConflict unless the user's Version matches the stored one, storing
+// it with the next version otherwise.
 type UserRepository interface {
 	Create(ctx context.Context, user User) (*User, error)
 	Update(ctx context.Context, user User) (*User, error)
@@ -11,3 +20,140 @@ type UserRepository interface {
 	List(ctx context.Context) ([]User, error)
 	Delete(ctx context.Context, id string) error
 }
+
+// MemoryUserRepository is a UserRepository, UserQuerier,
+// UsernameSkeletonFinder and CanonicalEmailFinder kept in process memory. It
+// hands out copies, so callers never share state with the store.
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]User
+	seq   int
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: map[string]User{}}
+}
+
+func (m *MemoryUserRepository) Create(ctx context.Context, user User) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user.ID == "" {
+		m.seq++
+		user.ID = strconv.Itoa(m.seq)
+	}
+	if _, exists := m.users[user.ID]; exists {
+		return nil, fmt.Errorf("user %s already exists", user.ID)
+	}
+	user.Version = 1
+	m.users[user.ID] = cloneUser(user)
+	onRollback(ctx, m.restore(user.ID, nil))
+	return &user, nil
+}
+
+func (m *MemoryUserRepository) Update(ctx context.Context, user User) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, exists := m.users[user.ID]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	if user.Version != stored.Version {
+		return nil, ErrConflict
+	}
+	user.Version++
+	m.users[user.ID] = cloneUser(user)
+	onRollback(ctx, m.restore(user.ID, &stored))
+	return &user, nil
+}
+
+func (m *MemoryUserRepository) GetByID(ctx context.Context, id string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user = cloneUser(user)
+	return &user, nil
+}
+
+func (m *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return m.find(func(u User) bool { return u.Email == email })
+}
+
+func (m *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	return m.find(func(u User) bool { return u.Username == username })
+}
+
+func (m *MemoryUserRepository) GetByUsernameSkeleton(ctx context.Context, skeleton string) (*User, error) {
+	return m.find(func(u User) bool { return UsernameSkeleton(u.Username) == skeleton })
+}
+
+func (m *MemoryUserRepository) GetByCanonicalEmail(ctx context.Context, canonical string) (*User, error) {
+	return m.find(func(u User) bool { return CanonicalEmail(u.Email) == canonical })
+}
+
+func (m *MemoryUserRepository) find(match func(User) bool) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if match(user) {
+			user = cloneUser(user)
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (m *MemoryUserRepository) List(ctx context.Context) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := make([]User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, cloneUser(user))
+	}
+	return users, nil
+}
+
+func (m *MemoryUserRepository) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, exists := m.users[id]; exists {
+		delete(m.users, id)
+		onRollback(ctx, m.restore(id, &stored))
+	}
+	return nil
+}
+
+func (m *MemoryUserRepository) QueryUsers(ctx context.Context, q ListUsersQuery) (*UserPage, error) {
+	users, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return FilterUsers(users, q)
+}
+
+// restore returns a rollback step putting back previous, or removing the user
+// when previous is nil.
+func (m *MemoryUserRepository) restore(id string, previous *User) func() {
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if previous == nil {
+			delete(m.users, id)
+		} else {
+			m.users[id] = *previous
+		}
+	}
+}
+
+func cloneUser(user User) User {
+	user.Attributes = maps.Clone(user.Attributes)
+	return user
+}
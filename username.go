@@ -0,0 +1,174 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// UsernamePolicy decides which usernames Register and user updates accept.
+// Rules apply to the normalized form; see NormalizeUsername.
+type UsernamePolicy struct {
+	MinLength int
+	MaxLength int
+	// AllowedRune reports whether r may appear in a username.
+	AllowedRune func(r rune) bool
+	// Reserved names are rejected, together with anything confusable with
+	// them.
+	Reserved []string
+}
+
+// DefaultUsernamePolicy accepts 1 to 64 letters, digits, dots, underscores
+// and hyphens, and reserves names that could pass for staff or system
+// accounts.
+func DefaultUsernamePolicy() UsernamePolicy {
+	return UsernamePolicy{
+		MinLength: 1,
+		MaxLength: 64,
+		AllowedRune: func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-", r)
+		},
+		Reserved: []string{
+			"abuse", "admin", "administrator", "api", "hostmaster", "moderator", "noreply",
+			"postmaster", "root", "security", "superuser", "support", "system", "webmaster",
+		},
+	}
+}
+
+func WithUsernamePolicy(policy UsernamePolicy) ServiceOption {
+	return func(s *Service) {
+		s.usernamePolicy = policy
+	}
+}
+
+// NormalizeUsername trims username, applies NFKC and folds its case, so that
+// visually equal spellings such as "Alice", "alice" and "ａｌｉｃｅ" are
+// stored the same way.
+func NormalizeUsername(username string) string {
+	username = norm.NFKC.String(strings.TrimSpace(username))
+	return norm.NFKC.String(cases.Fold().String(username))
+}
+
+// Validate checks a normalized username against the policy. Problems are
+// reported as a ValidationError on the "username" field.
+func (p UsernamePolicy) Validate(username string) error {
+	invalid := func(code FieldCode, sentinel error, message string) error {
+		return invalidField("username", code, sentinel, message)
+	}
+
+	length := utf8.RuneCountInString(username)
+	switch {
+	case length == 0:
+		return invalid(CodeRequired, ErrInvalidUsername, "is required")
+	case length < p.MinLength:
+		return invalid(CodeTooShort, ErrInvalidUsername, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	case p.MaxLength > 0 && length > p.MaxLength:
+		return invalid(CodeTooLong, ErrInvalidUsername, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+	if p.AllowedRune != nil {
+		if i := strings.IndexFunc(username, func(r rune) bool { return !p.AllowedRune(r) }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(username[i:])
+			return invalid(CodeInvalidCharacter, ErrInvalidUsername, fmt.Sprintf("must not contain %q", r))
+		}
+	}
+	skeleton := UsernameSkeleton(username)
+	if slices.ContainsFunc(p.Reserved, func(reserved string) bool { return UsernameSkeleton(reserved) == skeleton }) {
+		return invalid(CodeReserved, ErrUsernameReserved, "is reserved")
+	}
+	return nil
+}
+
+// UsernameSkeleton maps a username to the form it is compared by: two
+// usernames with the same skeleton look alike, like "admin" and "аdmin"
+// with a Cyrillic "а". It follows the skeleton algorithm of Unicode TR39 with
+// a subset of its confusables table covering Latin lookalikes.
+func UsernameSkeleton(username string) string {
+	decomposed := norm.NFD.String(NormalizeUsername(username))
+	mapped := strings.Map(func(r rune) rune {
+		if latin, ok := confusables[r]; ok {
+			return latin
+		}
+		return r
+	}, decomposed)
+	return norm.NFD.String(mapped)
+}
+
+// confusables maps characters that are indistinguishable from a Latin letter
+// or digit in common fonts to that letter, after case folding.
+var confusables = map[rune]rune{
+	// Digits
+	'0': 'o', '1': 'l',
+	// Latin
+	'ı': 'i', 'ɩ': 'i', 'ǀ': 'l', 'ɑ': 'a', 'ɡ': 'g', 'ʋ': 'u',
+	// Greek
+	'α': 'a', 'ϲ': 'c', 'η': 'n', 'ι': 'i', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'υ': 'u', 'χ': 'x', 'γ': 'y',
+	// Cyrillic
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j',
+	'ӏ': 'l', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'ԝ': 'w', 'х': 'x',
+	'у': 'y',
+	// Armenian
+	'օ': 'o', 'ս': 'u', 'ո': 'n', 'հ': 'h', 'զ': 'q',
+}
+
+// UsernameSkeletonFinder is implemented by user repositories that can look a
+// user up by UsernameSkeleton, typically through a unique index on it.
+// Service falls back to scanning UserRepository.List otherwise.
+type UsernameSkeletonFinder interface {
+	GetByUsernameSkeleton(ctx context.Context, skeleton string) (*User, error)
+}
+
+// checkUsername validates username and makes sure it is available to
+// userID. It returns the normalized form to store.
+func (s *Service) checkUsername(ctx context.Context, username, userID string) (string, error) {
+	username, err := s.validateUsername(username)
+	if err != nil {
+		return "", err
+	}
+	return username, s.ensureUsernameAvailable(ctx, username, userID)
+}
+
+// validateUsername normalizes username and checks it against the policy.
+func (s *Service) validateUsername(username string) (string, error) {
+	username = NormalizeUsername(username)
+	return username, s.usernamePolicy.Validate(username)
+}
+
+// ensureUsernameAvailable makes sure no user other than userID has the
+// normalized username or one confusable with it.
+func (s *Service) ensureUsernameAvailable(ctx context.Context, username, userID string) error {
+	existing, err := s.findByUsernameSkeleton(ctx, UsernameSkeleton(username))
+	if errors.Is(err, ErrUserNotFound) || (err == nil && existing.ID == userID) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFailedToListUsers, err)
+	}
+	if NormalizeUsername(existing.Username) == username {
+		return invalidField("username", CodeTaken, ErrUsernameAlreadyExists, "is already taken")
+	}
+	return invalidField("username", CodeTaken, ErrUsernameAlreadyExists, "is confusable with an existing username")
+}
+
+func (s *Service) findByUsernameSkeleton(ctx context.Context, skeleton string) (*User, error) {
+	if finder, ok := s.userRepo.(UsernameSkeletonFinder); ok {
+		return finder.GetByUsernameSkeleton(ctx, skeleton)
+	}
+	users, err := s.userRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if UsernameSkeleton(user.Username) == skeleton {
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
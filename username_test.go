@@ -0,0 +1,86 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	require.Equal(t, "alice", NormalizeUsername("  Alice "))
+	require.Equal(t, "alice", NormalizeUsername("ＡＬＩＣＥ"), "fullwidth forms")
+	require.Equal(t, "strasse", NormalizeUsername("STRAßE"))
+	require.Equal(t, "caf\u00e9", NormalizeUsername("cafe\u0301"), "combining accent")
+}
+
+func TestUsernameSkeleton(t *testing.T) {
+	require.Equal(t, UsernameSkeleton("admin"), UsernameSkeleton("\u0430dmin"), "Cyrillic a")
+	require.Equal(t, UsernameSkeleton("paypal"), UsernameSkeleton("\u0440\u0430y\u0440\u0430l"))
+	require.Equal(t, UsernameSkeleton("bob"), UsernameSkeleton("b0b"))
+	require.NotEqual(t, UsernameSkeleton("bob"), UsernameSkeleton("rob"))
+}
+
+func TestUsernamePolicy(t *testing.T) {
+	policy := DefaultUsernamePolicy()
+
+	require.NoError(t, policy.Validate("jane.doe_99"))
+	require.NoError(t, policy.Validate("josé"))
+	require.ErrorIs(t, policy.Validate(""), ErrInvalidUsername)
+	require.ErrorIs(t, policy.Validate("jane doe"), ErrInvalidUsername)
+	require.ErrorIs(t, policy.Validate("jane\u200bdoe"), ErrInvalidUsername, "zero-width space")
+	require.ErrorIs(t, policy.Validate("admin"), ErrUsernameReserved)
+	require.ErrorIs(t, policy.Validate("\u0430dmin"), ErrUsernameReserved)
+
+	strict := UsernamePolicy{MinLength: 3, MaxLength: 8, AllowedRune: func(r rune) bool { return r < unicode.MaxASCII }}
+	require.ErrorIs(t, strict.Validate("jo"), ErrInvalidUsername)
+	require.ErrorIs(t, strict.Validate("jonathan1"), ErrInvalidUsername)
+	require.ErrorIs(t, strict.Validate("josé"), ErrInvalidUsername)
+	require.NoError(t, strict.Validate("admin"))
+}
+
+func TestUsernameUniqueness(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewMemoryUserRepository(), NewMemoryRoleRepository(), &mockHasher{}, &mockTokenizer{})
+
+	alice, err := svc.Register(ctx, UserRegisterInput{Email: "alice@example.com", Username: " Alice ", Password: "pw"})
+	require.NoError(t, err)
+	require.Equal(t, "alice", alice.Username)
+
+	_, err = svc.Register(ctx, UserRegisterInput{Email: "other@example.com", Username: "ALICE", Password: "pw"})
+	require.ErrorIs(t, err, ErrUsernameAlreadyExists)
+	_, err = svc.Register(ctx, UserRegisterInput{Email: "other@example.com", Username: "\u0430lice", Password: "pw"})
+	require.ErrorIs(t, err, ErrUsernameAlreadyExists)
+	require.ErrorContains(t, err, "confusable")
+
+	bob, err := svc.Register(ctx, UserRegisterInput{Email: "bob@example.com", Username: "bob", Password: "pw"})
+	require.NoError(t, err)
+
+	t.Run("update", func(t *testing.T) {
+		taken := *bob
+		taken.Username = "Alice"
+		_, err := svc.UpdateUser(ctx, taken)
+		require.ErrorIs(t, err, ErrUsernameAlreadyExists)
+
+		renamed := *bob
+		renamed.Username = "Robert"
+		updated, err := svc.UpdateUser(ctx, renamed)
+		require.NoError(t, err)
+		require.Equal(t, "robert", updated.Username)
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		_, err := svc.UpdateUserProfile(ctx, alice.ID, UserPatch{Username: ptr("r\u043ebert")})
+		require.ErrorIs(t, err, ErrUsernameAlreadyExists)
+
+		updated, err := svc.UpdateUserProfile(ctx, alice.ID, UserPatch{Username: ptr("ALICE")})
+		require.NoError(t, err)
+		require.Equal(t, alice.Version, updated.Version, "renaming to the same normalized name is a no-op")
+	})
+
+	t.Run("reserved", func(t *testing.T) {
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "root@example.com", Username: "Root", Password: "pw"})
+		require.ErrorIs(t, err, ErrUsernameReserved)
+	})
+}
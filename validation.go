@@ -0,0 +1,86 @@
+package users
+
+import (
+	"errors"
+	"strings"
+)
+
+// FieldCode says what is wrong with a field, in a form clients can switch on.
+type FieldCode string
+
+const (
+	CodeRequired         FieldCode = "required"
+	CodeTooShort         FieldCode = "too_short"
+	CodeTooLong          FieldCode = "too_long"
+	CodeInvalidFormat    FieldCode = "invalid_format"
+	CodeInvalidCharacter FieldCode = "invalid_character"
+	CodeReserved         FieldCode = "reserved"
+	CodeBlocked          FieldCode = "blocked"
+	CodeTaken            FieldCode = "taken"
+	CodeSameAsCurrent    FieldCode = "same_as_current"
+)
+
+// FieldError is one problem with one input field. Field uses the JSON name
+// of the input, such as "email" or "new_password".
+type FieldError struct {
+	Field   string    `json:"field"`
+	Code    FieldCode `json:"code"`
+	Message string    `json:"message"`
+
+	// err is the sentinel from errors.go this problem corresponds to.
+	err error
+}
+
+// ValidationError reports every invalid field of an input at once. It
+// unwraps to the sentinel of each field, so errors.Is(err, ErrEmailTaken)
+// keeps working for callers that do not inspect the fields.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		if field.err != nil {
+			errs = append(errs, field.err)
+		}
+	}
+	return errs
+}
+
+// invalidField returns a ValidationError for a single field.
+func invalidField(field string, code FieldCode, sentinel error, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Code: code, Message: message, err: sentinel}}}
+}
+
+// add records a problem with field.
+func (e *ValidationError) add(field string, code FieldCode, sentinel error, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message, err: sentinel})
+}
+
+// collect moves the fields of a ValidationError into e so that several
+// checks can be reported together. Any other error is returned unchanged.
+func (e *ValidationError) collect(err error) error {
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		e.Fields = append(e.Fields, invalid.Fields...)
+		return nil
+	}
+	return err
+}
+
+// orNil returns e, or nil when no field was invalid.
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
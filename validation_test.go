@@ -0,0 +1,66 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationError(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(NewMemoryUserRepository(), NewMemoryRoleRepository(), &mockHasher{}, &mockTokenizer{})
+
+	t.Run("register reports every field", func(t *testing.T) {
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "bob", Username: "root"})
+		var invalid *ValidationError
+		require.ErrorAs(t, err, &invalid)
+		require.Equal(t, []FieldError{
+			{Field: "email", Code: CodeInvalidFormat, Message: "must contain @", err: ErrInvalidEmail},
+			{Field: "username", Code: CodeReserved, Message: "is reserved", err: ErrUsernameReserved},
+			{Field: "password", Code: CodeRequired, Message: "is required", err: ErrInvalidPassword},
+		}, invalid.Fields)
+		require.ErrorIs(t, err, ErrInvalidEmail)
+		require.ErrorIs(t, err, ErrUsernameReserved)
+		require.NotErrorIs(t, err, ErrEmailTaken)
+	})
+
+	t.Run("register reports taken fields together", func(t *testing.T) {
+		_, err := svc.Register(ctx, UserRegisterInput{Email: "bob@example.com", Username: "bob", Password: "pw"})
+		require.NoError(t, err)
+
+		_, err = svc.Register(ctx, UserRegisterInput{Email: "bob@example.com", Username: "bob", Password: "pw"})
+		var invalid *ValidationError
+		require.ErrorAs(t, err, &invalid)
+		require.Len(t, invalid.Fields, 2)
+		require.ErrorIs(t, err, ErrEmailTaken)
+		require.ErrorIs(t, err, ErrUsernameAlreadyExists)
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		user, err := svc.Register(ctx, UserRegisterInput{Email: "carol@example.com", Username: "carol", Password: "pw"})
+		require.NoError(t, err)
+
+		_, err = svc.ChangePassword(ctx, user.ID, "pw", "pw")
+		var invalid *ValidationError
+		require.ErrorAs(t, err, &invalid)
+		require.Equal(t, "new_password", invalid.Fields[0].Field)
+		require.Equal(t, CodeSameAsCurrent, invalid.Fields[0].Code)
+		require.ErrorIs(t, err, ErrCannotUseSamePassword)
+
+		_, err = svc.ChangePassword(ctx, user.ID, "pw", "")
+		require.ErrorAs(t, err, &invalid)
+		require.Equal(t, CodeRequired, invalid.Fields[0].Code)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		err := invalidField("email", CodeTaken, ErrEmailTaken, "is already taken")
+		data, jsonErr := json.Marshal(err)
+		require.NoError(t, jsonErr)
+		require.JSONEq(t, `{"fields":[{"field":"email","code":"taken","message":"is already taken"}]}`, string(data))
+		require.Equal(t, "validation failed: email: is already taken", err.Error())
+		require.True(t, errors.Is(err, ErrEmailTaken))
+	})
+}
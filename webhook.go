@@ -0,0 +1,495 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const PermissionWebhooksManage = "webhooks:manage"
+
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+
+	// DefaultWebhookTolerance is how old a signature timestamp may be before
+	// VerifyWebhookSignature rejects it as a possible replay.
+	DefaultWebhookTolerance = 5 * time.Minute
+
+	// MinWebhookSecretLength is the shortest signing secret
+	// CreateWebhookSubscription accepts. Anyone who can guess the secret can
+	// forge deliveries.
+	MinWebhookSecretLength = 32
+)
+
+// WebhookSubscription delivers the events named in Events, or every event
+// when Events is empty, to URL. A subscription with an OrganizationID only
+// receives events about members of that organization and can be managed by
+// its organization admins; one without receives the events of every user.
+type WebhookSubscription struct {
+	ID             string
+	URL            string
+	Events         []string
+	OrganizationID string
+	Secret         string
+	CreatedAt      time.Time
+}
+
+func (s WebhookSubscription) matches(eventType string) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, eventType) || slices.Contains(s.Events, AllEvents)
+}
+
+// WebhookDelivery is the log entry for a single delivery attempt.
+type WebhookDelivery struct {
+	SubscriptionID string
+	EventType      string
+	UserID         string
+	Attempt        int
+	StatusCode     int
+	Error          string
+	Duration       time.Duration
+	DeliveredAt    time.Time
+}
+
+func (d WebhookDelivery) Succeeded() bool {
+	return d.Error == "" && d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+// WebhookRetry is a delivery to one subscription that failed and waits for
+// its next attempt. Body is the payload as first built, so every attempt
+// sends the same bytes.
+type WebhookRetry struct {
+	ID             string
+	SubscriptionID string
+	EventType      string
+	UserID         string
+	Body           []byte
+	Attempts       int
+	NextAttemptAt  time.Time
+}
+
+// WebhookPayload is the JSON body posted to subscribers.
+type WebhookPayload struct {
+	Type       string          `json:"type"`
+	UserID     string          `json:"user_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func WithWebhookRepository(repo WebhookRepository) ServiceOption {
+	return func(s *Service) {
+		s.webhookRepo = repo
+	}
+}
+
+// CreateWebhookSubscription registers a callback URL, which must be https and
+// must not name a loopback, private or link-local host. A signing secret is
+// generated when sub.Secret is empty; it is returned only here.
+func (s *Service) CreateWebhookSubscription(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, error) {
+	if s.webhookRepo == nil {
+		return nil, ErrWebhooksNotConfigured
+	}
+	if err := s.authorizeWebhook(ctx, sub); err != nil {
+		return nil, err
+	}
+	if sub.OrganizationID != "" {
+		if _, err := s.orgRepo.GetByID(ctx, sub.OrganizationID); err != nil {
+			return nil, ErrOrganizationNotFound
+		}
+	}
+
+	if err := checkWebhookURL(sub.URL); err != nil {
+		return nil, err
+	}
+	if sub.Secret != "" && len(sub.Secret) < MinWebhookSecretLength {
+		return nil, fmt.Errorf("%w: must be at least %d bytes", ErrInvalidWebhookSecret, MinWebhookSecretLength)
+	}
+
+	if sub.Secret == "" {
+		secret, err := randomPseudonym()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedToCreateWebhook, err)
+		}
+		sub.Secret = secret
+	}
+	sub.CreatedAt = s.now()
+
+	created, err := s.webhookRepo.Create(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToCreateWebhook, err)
+	}
+
+	if err := s.audit(ctx, AuditWebhookCreated, "webhook", created.ID, nil, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// checkWebhookURL rejects targets that would let a subscriber make the
+// dispatcher call into its own network. Host names are resolved only when
+// delivering, where the dispatcher's default client checks them again.
+func checkWebhookURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Scheme != "https" || target.Hostname() == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s is not a public host", ErrInvalidWebhookURL, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return fmt.Errorf("%w: %s is not a public address", ErrInvalidWebhookURL, host)
+	}
+	return nil
+}
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// publicDialer refuses connections to addresses that publicAddr rejects,
+// which also covers host names resolving to them.
+func publicDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !publicAddr(addr) {
+				return fmt.Errorf("%w: %s is not a public address", ErrInvalidWebhookURL, host)
+			}
+			return nil
+		},
+	}
+}
+
+func (s *Service) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	if s.webhookRepo == nil {
+		return ErrWebhooksNotConfigured
+	}
+	if _, err := s.authorizedWebhook(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return s.audit(ctx, AuditWebhookDeleted, "webhook", id, nil, nil)
+}
+
+func (s *Service) ListWebhookDeliveries(ctx context.Context, subscriptionID string) ([]WebhookDelivery, error) {
+	if s.webhookRepo == nil {
+		return nil, ErrWebhooksNotConfigured
+	}
+	if _, err := s.authorizedWebhook(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, subscriptionID)
+}
+
+// authorizedWebhook loads the subscription id and checks that the actor may
+// manage it. Only actors allowed to manage every subscription learn that an
+// unknown id does not exist.
+func (s *Service) authorizedWebhook(ctx context.Context, id string) (*WebhookSubscription, error) {
+	sub, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		if err := s.authorize(ctx, PermissionWebhooksManage, Resource{Type: "webhook", ID: id}); err != nil {
+			return nil, err
+		}
+		return nil, ErrWebhookNotFound
+	}
+	if err := s.authorizeWebhook(ctx, *sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// authorizeWebhook checks webhooks:manage for sub, granted either globally or,
+// for an organization's subscription, by the actor's role in it.
+func (s *Service) authorizeWebhook(ctx context.Context, sub WebhookSubscription) error {
+	if sub.OrganizationID == "" {
+		return s.authorize(ctx, PermissionWebhooksManage, Resource{Type: "webhook", ID: sub.ID})
+	}
+	if s.orgRepo == nil {
+		return ErrOrganizationsNotConfigured
+	}
+	return s.authorizeInOrganization(ctx, PermissionWebhooksManage, sub.OrganizationID)
+}
+
+type WebhookDispatcherConfig struct {
+	// Client defaults to one that only connects to public addresses. A
+	// custom Client is used as is.
+	Client         *http.Client
+	PollInterval   time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Clock          func() time.Time
+
+	// Organizations resolves the OrganizationID filter of subscriptions.
+	// Without it, organization subscriptions receive nothing.
+	Organizations OrganizationRepository
+}
+
+// WebhookDispatcher is an EventPublisher that posts signed events to every
+// matching subscription. Failed deliveries are retried per subscription with
+// exponential backoff by RetryDue, which Run calls periodically. Subscribe it
+// to an EventBus or hand it to an OutboxRelay.
+type WebhookDispatcher struct {
+	repo WebhookRepository
+	cfg  WebhookDispatcherConfig
+}
+
+func NewWebhookDispatcher(repo WebhookRepository, cfg WebhookDispatcherConfig) *WebhookDispatcher {
+	if cfg.Client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// A proxy would make the connection on the dispatcher's behalf,
+		// past the dialer's check.
+		transport.Proxy = nil
+		transport.DialContext = publicDialer().DialContext
+		cfg.Client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return &WebhookDispatcher{repo: repo, cfg: cfg}
+}
+
+// Publish makes one delivery attempt to each matching subscription. A
+// subscription that fails is scheduled for RetryDue instead of being retried
+// here, so Publish never waits out a backoff and a publisher that retries
+// Publish itself, such as OutboxRelay, does not resend the event to the
+// subscriptions that already have it. Publish only fails when the repository
+// does.
+func (d *WebhookDispatcher) Publish(ctx context.Context, event Event) error {
+	subs, err := d.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := webhookBody(event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		if !sub.matches(event.EventType()) || !d.inScope(ctx, sub, event.AggregateID()) {
+			continue
+		}
+		retry := WebhookRetry{SubscriptionID: sub.ID, EventType: event.EventType(), UserID: event.AggregateID(), Body: body}
+		if _, err := d.attempt(ctx, sub, retry); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %v", ErrWebhookDeliveryFailed, sub.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// inScope reports whether sub may receive events about userID.
+func (d *WebhookDispatcher) inScope(ctx context.Context, sub WebhookSubscription, userID string) bool {
+	if sub.OrganizationID == "" {
+		return true
+	}
+	if d.cfg.Organizations == nil {
+		return false
+	}
+	_, err := d.cfg.Organizations.GetMembership(ctx, sub.OrganizationID, userID)
+	return err == nil
+}
+
+// RetryDue makes the next attempt of every failed delivery whose backoff has
+// elapsed and returns how many of them succeeded.
+func (d *WebhookDispatcher) RetryDue(ctx context.Context) (int, error) {
+	retries, err := d.repo.ListDueRetries(ctx, d.cfg.Clock())
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, retry := range retries {
+		sub, err := d.repo.GetByID(ctx, retry.SubscriptionID)
+		if err != nil {
+			// The subscription was deleted, so there is nobody left to
+			// deliver to.
+			if err := d.repo.DeleteRetry(ctx, retry.ID); err != nil {
+				return succeeded, err
+			}
+			continue
+		}
+
+		ok, err := d.attempt(ctx, *sub, retry)
+		if err != nil {
+			return succeeded, err
+		}
+		if ok {
+			succeeded++
+		}
+	}
+	return succeeded, nil
+}
+
+// Run calls RetryDue every PollInterval until ctx is cancelled. It is meant
+// to be started in its own goroutine.
+func (d *WebhookDispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Storage errors are transient from the dispatcher's point of view;
+		// the next tick tries again.
+		_, _ = d.RetryDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// attempt posts retry.Body to sub once and logs the attempt. On failure the
+// retry is scheduled after an exponential backoff until MaxAttempts is
+// reached; otherwise it is cleared. attempt reports whether the delivery
+// succeeded.
+func (d *WebhookDispatcher) attempt(ctx context.Context, sub WebhookSubscription, retry WebhookRetry) (bool, error) {
+	retry.Attempts++
+	delivery := d.post(ctx, sub, retry)
+	delivery.Attempt = retry.Attempts
+	if err := d.repo.RecordDelivery(ctx, delivery); err != nil {
+		return false, err
+	}
+
+	ok := delivery.Succeeded()
+	if ok || retry.Attempts >= d.cfg.MaxAttempts {
+		if retry.ID == "" {
+			return ok, nil
+		}
+		return ok, d.repo.DeleteRetry(ctx, retry.ID)
+	}
+
+	retry.NextAttemptAt = d.cfg.Clock().Add(exponentialBackoff(d.cfg.InitialBackoff, d.cfg.MaxBackoff, retry.Attempts))
+	_, err := d.repo.SaveRetry(ctx, retry)
+	return false, err
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, sub WebhookSubscription, retry WebhookRetry) WebhookDelivery {
+	start := d.cfg.Clock()
+	delivery := WebhookDelivery{
+		SubscriptionID: sub.ID,
+		EventType:      retry.EventType,
+		UserID:         retry.UserID,
+		DeliveredAt:    start,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(retry.Body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, retry.EventType)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(sub.Secret, start, retry.Body))
+
+	resp, err := d.cfg.Client.Do(req)
+	delivery.Duration = d.cfg.Clock().Sub(start)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	resp.Body.Close()
+	delivery.StatusCode = resp.StatusCode
+	return delivery
+}
+
+func webhookBody(event Event) ([]byte, error) {
+	var data []byte
+	if msg, ok := event.(OutboxMessage); ok {
+		data = msg.Payload
+	} else {
+		var err error
+		if data, err = json.Marshal(event); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(WebhookPayload{
+		Type:       event.EventType(),
+		UserID:     event.AggregateID(),
+		OccurredAt: event.OccurredAt(),
+		Data:       data,
+	})
+}
+
+// SignWebhookPayload returns the signature header value for body sent at ts:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func SignWebhookPayload(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + webhookMAC(secret, t, body)
+}
+
+// VerifyWebhookSignature checks a signature header produced by
+// SignWebhookPayload. Receivers should pass the raw request body and reject
+// signatures older than tolerance to limit replays.
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, sig string
+	for part := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			sig = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidWebhookSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(webhookMAC(secret, t, body))) {
+		return ErrInvalidWebhookSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrWebhookSignatureExpired
+	}
+	return nil
+}
+
+func webhookMAC(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
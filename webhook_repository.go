@@ -0,0 +1,25 @@
+package users
+
+import (
+	"context"
+	"time"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, error)
+	Delete(ctx context.Context, id string) error
+	GetByID(ctx context.Context, id string) (*WebhookSubscription, error)
+	List(ctx context.Context) ([]WebhookSubscription, error)
+
+	RecordDelivery(ctx context.Context, delivery WebhookDelivery) error
+	// ListDeliveries returns the delivery attempts for a subscription, oldest
+	// first.
+	ListDeliveries(ctx context.Context, subscriptionID string) ([]WebhookDelivery, error)
+
+	// SaveRetry stores a retry, assigning its ID when empty and replacing the
+	// stored retry with the same ID otherwise.
+	SaveRetry(ctx context.Context, retry WebhookRetry) (*WebhookRetry, error)
+	// ListDueRetries returns the retries whose NextAttemptAt is not after now.
+	ListDueRetries(ctx context.Context, now time.Time) ([]WebhookRetry, error)
+	DeleteRetry(ctx context.Context, id string) error
+}
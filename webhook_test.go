@@ -0,0 +1,340 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockWebhookRepo struct {
+	mu         sync.Mutex
+	subs       map[string]*WebhookSubscription
+	deliveries []WebhookDelivery
+	retries    map[string]WebhookRetry
+	retrySeq   int
+}
+
+func newMockWebhookRepo() *mockWebhookRepo {
+	return &mockWebhookRepo{subs: map[string]*WebhookSubscription{}, retries: map[string]WebhookRetry{}}
+}
+
+func (m *mockWebhookRepo) Create(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sub.ID == "" {
+		sub.ID = "wh-" + strconv.Itoa(len(m.subs)+1)
+	}
+	m.subs[sub.ID] = &sub
+	return &sub, nil
+}
+
+func (m *mockWebhookRepo) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subs, id)
+	return nil
+}
+
+func (m *mockWebhookRepo) GetByID(ctx context.Context, id string) (*WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subs[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return sub, nil
+}
+
+func (m *mockWebhookRepo) List(ctx context.Context) ([]WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subs []WebhookSubscription
+	for _, sub := range m.subs {
+		subs = append(subs, *sub)
+	}
+	return subs, nil
+}
+
+func (m *mockWebhookRepo) RecordDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func (m *mockWebhookRepo) ListDeliveries(ctx context.Context, subscriptionID string) ([]WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var found []WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			found = append(found, d)
+		}
+	}
+	return found, nil
+}
+
+func (m *mockWebhookRepo) SaveRetry(ctx context.Context, retry WebhookRetry) (*WebhookRetry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if retry.ID == "" {
+		m.retrySeq++
+		retry.ID = "retry-" + strconv.Itoa(m.retrySeq)
+	}
+	m.retries[retry.ID] = retry
+	return &retry, nil
+}
+
+func (m *mockWebhookRepo) ListDueRetries(ctx context.Context, now time.Time) ([]WebhookRetry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []WebhookRetry
+	for _, retry := range m.retries {
+		if !retry.NextAttemptAt.After(now) {
+			due = append(due, retry)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockWebhookRepo) DeleteRetry(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.retries, id)
+	return nil
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	svc, _ := newEnforcingService()
+	repo := newMockWebhookRepo()
+	svc.webhookRepo = repo
+	ctx := actorCtx("admin")
+
+	sub, err := svc.CreateWebhookSubscription(ctx, WebhookSubscription{URL: "https://example.com/hook"})
+	require.NoError(t, err)
+	require.NotEmpty(t, sub.Secret)
+
+	for _, target := range []string{
+		"ftp://example.com",
+		"http://example.com/hook",
+		"https://localhost/hook",
+		"https://127.0.0.1/hook",
+		"https://10.0.0.5/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+		"https://[::ffff:192.168.1.1]/hook",
+	} {
+		_, err = svc.CreateWebhookSubscription(ctx, WebhookSubscription{URL: target})
+		require.ErrorIs(t, err, ErrInvalidWebhookURL, target)
+	}
+
+	_, err = svc.CreateWebhookSubscription(ctx, WebhookSubscription{URL: "https://example.com/hook", Secret: "s3cret"})
+	require.ErrorIs(t, err, ErrInvalidWebhookSecret)
+	custom, err := svc.CreateWebhookSubscription(ctx, WebhookSubscription{URL: "https://example.com/hook", Secret: strings.Repeat("s", MinWebhookSecretLength)})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteWebhookSubscription(ctx, custom.ID))
+
+	_, err = svc.CreateWebhookSubscription(actorCtx("alice"), WebhookSubscription{URL: "https://example.com/hook"})
+	require.ErrorIs(t, err, ErrForbidden)
+
+	_, err = svc.ListWebhookDeliveries(ctx, "missing")
+	require.ErrorIs(t, err, ErrWebhookNotFound)
+
+	require.NoError(t, svc.DeleteWebhookSubscription(ctx, sub.ID))
+	require.Empty(t, repo.subs)
+
+	t.Run("not configured", func(t *testing.T) {
+		svc := NewService(&mockUserRepo{}, &mockRoleRepo{}, &mockHasher{}, &mockTokenizer{})
+		_, err := svc.CreateWebhookSubscription(ctx, WebhookSubscription{URL: "https://example.com"})
+		require.ErrorIs(t, err, ErrWebhooksNotConfigured)
+	})
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu       sync.Mutex
+		calls    int
+		received []WebhookPayload
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := VerifyWebhookSignature("s3cret", r.Header.Get(WebhookSignatureHeader), body, DefaultWebhookTolerance, time.Now())
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload WebhookPayload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	clock := &testClock{now: now}
+	repo := newMockWebhookRepo()
+	repo.subs["wh-1"] = &WebhookSubscription{ID: "wh-1", URL: server.URL, Secret: "s3cret", Events: []string{EventUserDeleted}}
+	dispatcher := NewWebhookDispatcher(repo, WebhookDispatcherConfig{Client: server.Client(), InitialBackoff: time.Second, Clock: clock.Now})
+
+	require.NoError(t, dispatcher.Publish(ctx, UserLoggedIn{EventMeta{UserID: "alice"}}))
+	require.Zero(t, calls, "filtered out by the subscription")
+
+	require.NoError(t, dispatcher.Publish(ctx, UserDeleted{EventMeta{UserID: "alice", ActorID: "admin"}}), "failure is retried later")
+	require.Equal(t, 1, calls)
+	require.Len(t, repo.retries, 1)
+
+	n, err := dispatcher.RetryDue(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n, "backoff has not elapsed")
+
+	clock.now = now.Add(time.Second)
+	n, err = dispatcher.RetryDue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Empty(t, repo.retries)
+	require.Len(t, received, 1)
+	require.Equal(t, EventUserDeleted, received[0].Type)
+	require.Equal(t, "alice", received[0].UserID)
+	require.JSONEq(t, `{"user_id":"alice","actor_id":"admin","at":"0001-01-01T00:00:00Z"}`, string(received[0].Data))
+
+	deliveries, err := repo.ListDeliveries(ctx, "wh-1")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, http.StatusServiceUnavailable, deliveries[0].StatusCode)
+	require.False(t, deliveries[0].Succeeded())
+	require.Equal(t, 2, deliveries[1].Attempt)
+	require.True(t, deliveries[1].Succeeded())
+}
+
+func TestWebhookDispatcherRefusesPrivateTargets(t *testing.T) {
+	ctx := context.Background()
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	repo := newMockWebhookRepo()
+	repo.subs["wh-1"] = &WebhookSubscription{ID: "wh-1", URL: server.URL, Secret: "s"}
+	dispatcher := NewWebhookDispatcher(repo, WebhookDispatcherConfig{})
+
+	require.NoError(t, dispatcher.Publish(ctx, UserDeleted{EventMeta{UserID: "alice"}}))
+	require.False(t, called)
+	require.Len(t, repo.deliveries, 1)
+	require.Contains(t, repo.deliveries[0].Error, "not a public address")
+}
+
+func TestWebhookDispatcherGivesUp(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	clock := &testClock{now: time.Now()}
+	repo := newMockWebhookRepo()
+	repo.subs["wh-1"] = &WebhookSubscription{ID: "wh-1", URL: server.URL, Secret: "s"}
+	dispatcher := NewWebhookDispatcher(repo, WebhookDispatcherConfig{Client: server.Client(), MaxAttempts: 3, Clock: clock.Now})
+
+	require.NoError(t, dispatcher.Publish(ctx, UserDeleted{EventMeta{UserID: "alice"}}))
+	for range 3 {
+		clock.now = clock.now.Add(time.Hour)
+		_, err := dispatcher.RetryDue(ctx)
+		require.NoError(t, err)
+	}
+	require.Len(t, repo.deliveries, 3)
+	require.Empty(t, repo.retries)
+}
+
+func TestWebhookDispatcherRetriesOnlyFailedSubscription(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+		if r.URL.Path == "/down" && calls[r.URL.Path] == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	clock := &testClock{now: time.Now()}
+	repo := newMockWebhookRepo()
+	repo.subs["healthy"] = &WebhookSubscription{ID: "healthy", URL: server.URL + "/up", Secret: "s"}
+	repo.subs["flaky"] = &WebhookSubscription{ID: "flaky", URL: server.URL + "/down", Secret: "s"}
+	dispatcher := NewWebhookDispatcher(repo, WebhookDispatcherConfig{Client: server.Client(), Clock: clock.Now})
+
+	require.NoError(t, dispatcher.Publish(ctx, UserDeleted{EventMeta{UserID: "alice"}}))
+	clock.now = clock.now.Add(time.Minute)
+	n, err := dispatcher.RetryDue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, map[string]int{"/up": 1, "/down": 2}, calls)
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"user.deleted"}`)
+	header := SignWebhookPayload("secret", now, body)
+
+	require.NoError(t, VerifyWebhookSignature("secret", header, body, time.Minute, now.Add(30*time.Second)))
+	require.ErrorIs(t, VerifyWebhookSignature("other", header, body, time.Minute, now), ErrInvalidWebhookSignature)
+	require.ErrorIs(t, VerifyWebhookSignature("secret", header, []byte(`{}`), time.Minute, now), ErrInvalidWebhookSignature)
+	require.ErrorIs(t, VerifyWebhookSignature("secret", header, body, time.Minute, now.Add(2*time.Minute)), ErrWebhookSignatureExpired)
+	require.ErrorIs(t, VerifyWebhookSignature("secret", "garbage", body, time.Minute, now), ErrInvalidWebhookSignature)
+}
+
+func TestOrganizationWebhooks(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload WebhookPayload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, payload.UserID)
+	}))
+	defer server.Close()
+
+	svc, orgRepo := newOrganizationService()
+	svc.enforceAuthz = true
+	repo := newMockWebhookRepo()
+	svc.webhookRepo = repo
+	orgRepo.memberships[membershipKey("acme", "alice")] = &Membership{OrganizationID: "acme", UserID: "alice", RoleID: "r-admin"}
+
+	sub, err := svc.CreateWebhookSubscription(actorCtx("alice"), WebhookSubscription{URL: "https://acme.example.com/hook", OrganizationID: "acme"})
+	require.NoError(t, err, "organization admins manage their organization's webhooks")
+	_, err = svc.CreateWebhookSubscription(actorCtx("alice"), WebhookSubscription{URL: "https://acme.example.com/hook"})
+	require.ErrorIs(t, err, ErrForbidden, "but not global ones")
+	_, err = svc.CreateWebhookSubscription(actorCtx("alice"), WebhookSubscription{URL: "https://acme.example.com/hook", OrganizationID: "globex"})
+	require.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.ListWebhookDeliveries(actorCtx("bob"), sub.ID)
+	require.ErrorIs(t, err, ErrForbidden)
+
+	repo.subs[sub.ID].URL = server.URL
+	dispatcher := NewWebhookDispatcher(repo, WebhookDispatcherConfig{Client: server.Client(), Organizations: orgRepo})
+	require.NoError(t, dispatcher.Publish(ctx, UserRegistered{EventMeta: EventMeta{UserID: "alice"}}))
+	require.NoError(t, dispatcher.Publish(ctx, UserRegistered{EventMeta: EventMeta{UserID: "bob"}}))
+	require.Equal(t, []string{"alice"}, received, "bob is not a member of acme")
+
+	_, err = svc.ListWebhookDeliveries(actorCtx("alice"), sub.ID)
+	require.NoError(t, err)
+}